@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/3ter/iMagine/scene"
+)
+
+// graph prints the story structure of the scene content and returns the exit code for the command.
+//
+// The optional argument is the format, 'dot' by default. Problems with the content are printed to stderr so they don't
+// end up in the graph.
+func graph(args []string) int {
+	format := `dot`
+	if len(args) > 1 {
+		fmt.Printf("Usage: graph [%s]\n", strings.Join(scene.GraphFormats, `|`))
+		return 2
+	}
+	if len(args) == 1 {
+		format = args[0]
+	}
+
+	output, problems, err := scene.ExportGraph(scene.ContentDir, format)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Print(output)
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found.\n", len(problems))
+		return 1
+	}
+	return 0
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 	"time"
 
 	"github.com/3ter/iMagine/scene"
@@ -21,6 +23,9 @@ func gameloop(win *pixelgl.Window) {
 	scene.SetWindowForAllScenes(win)
 
 	scene.LoadFilesToSceneMap()
+	// Content files are polled for changes so writers don't need to restart the game
+	scene.WatchContent(time.Second / 2)
+	scene.EnableAutosave()
 	scene.GlobalCurrentScene = `MainMenu`
 
 	for !win.Closed() {
@@ -35,9 +40,16 @@ func gameloop(win *pixelgl.Window) {
 			scene.GlobalScenes[scene.GlobalCurrentScene].Draw(win, start)
 		}
 
+		scene.ReloadChangedContent()
+
 		win.Update()
 		<-fps
 	}
+
+	// The game is saved however the window has been closed, an autosave which hasn't been written yet is outdated
+	if err := scene.SaveGame(); err != nil {
+		log.Println(err)
+	}
 }
 
 func run() {
@@ -58,5 +70,34 @@ func main() {
 	// to change the flags on the default logger to also print the location (e.g. log.Fatal("Foo"))
 	log.SetFlags(log.LstdFlags | log.Llongfile)
 
+	seed := flag.Int64("seed", 0, "seed for everything left to chance to repeat a playthrough (0 picks a random seed)")
+	language := flag.String("lang", "", "language of the content, e.g. 'de' to play the 'script.de.md' files")
+	isTTY := flag.Bool("tty", false, "play in the terminal without a window or sound (ambience is shown as annotations)")
+	flag.Parse()
+	if *seed != 0 {
+		scene.SeedRandom(*seed)
+	}
+	scene.SetLanguage(*language)
+
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case `lint`:
+			os.Exit(lint())
+		case `import-twee`:
+			os.Exit(importTwee(flag.Args()[1:]))
+		case `graph`:
+			os.Exit(graph(flag.Args()[1:]))
+		default:
+			log.Fatalf("Unknown command '%s' (available: lint, import-twee, graph)", flag.Arg(0))
+		}
+	}
+
+	if *isTTY {
+		if err := scene.RunTerminal(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	pixelgl.Run(run)
 }
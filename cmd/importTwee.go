@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/3ter/iMagine/scene"
+)
+
+// importTwee converts a Twine story into scene folders and returns the exit code for the command.
+//
+// The arguments are the Twee file and optionally the name of the scene folder for untagged passages.
+func importTwee(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: import-twee <story.twee> [SceneName]")
+		return 2
+	}
+	var sceneName string
+	if len(args) == 2 {
+		sceneName = args[1]
+	}
+
+	problems, err := scene.ImportTwee(args[0], scene.ContentDir, sceneName)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Imported with %d problem(s), run 'lint' after fixing them.\n", len(problems))
+		return 1
+	}
+	fmt.Println("Imported without problems.")
+	return 0
+}
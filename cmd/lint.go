@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/3ter/iMagine/scene"
+)
+
+// lint prints every problem found in the scene content and returns the exit code for the command.
+func lint() int {
+	problems := scene.LintContent(scene.ContentDir)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found.\n", len(problems))
+		return 1
+	}
+	fmt.Println("No problems found.")
+	return 0
+}
@@ -0,0 +1,254 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file splits the player's input into verb, object and an optional preposition with a second object.
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// VerbDictionaryFile is the file inside the content directory which configures verbs, directions and prepositions.
+const VerbDictionaryFile = `verbs.json`
+
+// playerCommand is the player's input after it has been parsed, e.g. 'use old key on cupboard'.
+type playerCommand struct {
+	// input is the command as typed by the player
+	input string
+	// verb is the canonical verb, e.g. 'take' for 'pick up'. Unknown verbs are kept as typed.
+	verb           string
+	object         string
+	preposition    string
+	indirectObject string
+}
+
+// String returns the canonical form of the command which is also used to look up script keywords.
+func (c playerCommand) String() string {
+	var parts []string
+	for _, part := range []string{c.verb, c.object, c.preposition, c.indirectObject} {
+		if part != `` {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ` `)
+}
+
+// verbDictionaryConfig is the structure of the verb dictionary file, e.g.
+//
+//	{
+//	    "verbs": {"take": ["pick up", "grab"]},
+//	    "directions": {"north": ["n"]},
+//	    "prepositions": ["on", "with"]
+//	}
+type verbDictionaryConfig struct {
+	// Verbs maps the canonical verb to its synonyms
+	Verbs map[string][]string
+	// Directions maps a direction to its abbreviations
+	Directions   map[string][]string
+	Prepositions []string
+}
+
+// verbDictionary is used to parse the player's input.
+type verbDictionary struct {
+	// verbPhrases maps every way to write a verb (e.g. 'pick up') to the canonical verb (e.g. 'take')
+	verbPhrases map[string]string
+	// maxVerbWords is the number of words of the longest verb phrase
+	maxVerbWords int
+	// directions maps directions and their abbreviations (e.g. 'n') to the direction (e.g. 'north')
+	directions   map[string]string
+	prepositions map[string]bool
+}
+
+// articles are ignored in the player's input, e.g. 'inspect the reflection'.
+var articles = map[string]bool{`a`: true, `an`: true, `the`: true}
+
+// builtinVerbs are handled by the engine if no script keyword matches, see 'handleActions'.
+var builtinVerbs = map[string]bool{
+	`go`: true, `look`: true, `take`: true, `drop`: true, `inventory`: true, `open`: true, `use`: true,
+}
+
+// directionVerbs are the built-in verbs whose object is a direction, e.g. 'go n' means 'go north'.
+var directionVerbs = map[string]bool{`go`: true, `look`: true}
+
+// globalVerbDictionary is replaced by the content's verb dictionary in 'LoadFilesToSceneMap'.
+var globalVerbDictionary = getDefaultVerbDictionary()
+
+// getDefaultVerbDictionary contains what the built-in verbs need to work without a verb dictionary file.
+func getDefaultVerbDictionary() *verbDictionary {
+	dictionary, _ := newVerbDictionary(verbDictionaryConfig{
+		Verbs: map[string][]string{
+			`go`:        {},
+			`look`:      {},
+			`take`:      {},
+			`drop`:      {},
+			`inventory`: {`i`},
+			`open`:      {},
+			`use`:       {},
+		},
+		Directions: map[string][]string{
+			`north`: {`n`},
+			`east`:  {`e`},
+			`south`: {`s`},
+			`west`:  {`w`},
+		},
+		Prepositions: []string{`on`, `with`},
+	})
+	return dictionary
+}
+
+func newVerbDictionary(config verbDictionaryConfig) (*verbDictionary, error) {
+	dictionary := &verbDictionary{
+		verbPhrases:  make(map[string]string),
+		directions:   make(map[string]string),
+		prepositions: make(map[string]bool),
+	}
+
+	addVerbPhrase := func(phrase, verb string) error {
+		phrase = strings.Join(strings.Fields(strings.ToLower(phrase)), ` `)
+		if previousVerb, isDefined := dictionary.verbPhrases[phrase]; isDefined && previousVerb != verb {
+			return fmt.Errorf("'%s' is used for the verbs '%s' and '%s'", phrase, previousVerb, verb)
+		}
+		dictionary.verbPhrases[phrase] = verb
+		if words := len(strings.Fields(phrase)); words > dictionary.maxVerbWords {
+			dictionary.maxVerbWords = words
+		}
+		return nil
+	}
+	for verb, synonyms := range config.Verbs {
+		verb = strings.ToLower(verb)
+		for _, phrase := range append([]string{verb}, synonyms...) {
+			if err := addVerbPhrase(phrase, verb); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for direction, abbreviations := range config.Directions {
+		direction = strings.ToLower(direction)
+		dictionary.directions[direction] = direction
+		for _, abbreviation := range abbreviations {
+			dictionary.directions[strings.ToLower(abbreviation)] = direction
+		}
+	}
+	for _, preposition := range config.Prepositions {
+		dictionary.prepositions[strings.ToLower(preposition)] = true
+	}
+
+	return dictionary, nil
+}
+
+// loadVerbDictionary reads the verb dictionary file. The built-in verbs and directions are always part of it.
+func loadVerbDictionary(filename string) (*verbDictionary, error) {
+	jsonBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config verbDictionaryConfig
+	if err := json.Unmarshal(jsonBytes, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	defaultDictionary := getDefaultVerbDictionary()
+	if config.Verbs == nil {
+		config.Verbs = make(map[string][]string)
+	}
+	for phrase, verb := range defaultDictionary.verbPhrases {
+		config.Verbs[verb] = append(config.Verbs[verb], phrase)
+	}
+	if config.Directions == nil {
+		config.Directions = make(map[string][]string)
+	}
+	for abbreviation, direction := range defaultDictionary.directions {
+		config.Directions[direction] = append(config.Directions[direction], abbreviation)
+	}
+	for preposition := range defaultDictionary.prepositions {
+		config.Prepositions = append(config.Prepositions, preposition)
+	}
+
+	dictionary, err := newVerbDictionary(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return dictionary, nil
+}
+
+// loadVerbDictionaryFromContent replaces the global verb dictionary if the content directory contains one.
+func loadVerbDictionaryFromContent(contentDir string) error {
+	dictionary, err := loadVerbDictionary(contentDir + VerbDictionaryFile)
+	if os.IsNotExist(err) {
+		globalVerbDictionary = getDefaultVerbDictionary()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	globalVerbDictionary = dictionary
+	return nil
+}
+
+// tokenizeCommand lower cases the input, strips punctuation and removes articles.
+func tokenizeCommand(input string) []string {
+	var tokens []string
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		word = strings.Trim(word, `.,!?;:"'`)
+		if word != `` && !articles[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// parseCommand splits the input into verb, object, preposition and indirect object.
+//
+// - The longest verb phrase at the start of the input is the verb: 'pick up old key' -> take / old key
+// - The first preposition after the object starts the indirect object: 'use key on cupboard' -> use / key / on / cupboard
+// - A direction on its own means going there: 'n' -> go / north
+func (d *verbDictionary) parseCommand(input string) playerCommand {
+	command := playerCommand{input: input}
+	tokens := tokenizeCommand(input)
+	if len(tokens) == 0 {
+		return command
+	}
+
+	if direction, isDirection := d.directions[tokens[0]]; isDirection && len(tokens) == 1 {
+		command.verb = `go`
+		command.object = direction
+		return command
+	}
+
+	verbWords := 1
+	command.verb = tokens[0]
+	for words := minInt(d.maxVerbWords, len(tokens)); words >= 1; words-- {
+		if verb, isVerb := d.verbPhrases[strings.Join(tokens[:words], ` `)]; isVerb {
+			command.verb = verb
+			verbWords = words
+			break
+		}
+	}
+	rest := tokens[verbWords:]
+
+	// 'go to north' or 'look at cupboard' without the preposition being part of the verb phrase
+	if len(rest) > 1 && d.prepositions[rest[0]] {
+		rest = rest[1:]
+	}
+	for idx := 1; idx < len(rest); idx++ {
+		if d.prepositions[rest[idx]] {
+			command.preposition = rest[idx]
+			command.indirectObject = strings.Join(rest[idx+1:], ` `)
+			rest = rest[:idx]
+			break
+		}
+	}
+	command.object = strings.Join(rest, ` `)
+
+	if direction, isDirection := d.directions[command.object]; isDirection && directionVerbs[command.verb] {
+		command.object = direction
+	}
+
+	return command
+}
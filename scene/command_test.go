@@ -0,0 +1,32 @@
+package scene
+
+import (
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	dictionary, err := loadVerbDictionary(ContentDir + VerbDictionaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputCommands := map[string]playerCommand{
+		`pick up the old key`:      {verb: `take`, object: `old key`},
+		`Use key on cupboard.`:     {verb: `use`, object: `key`, preposition: `on`, indirectObject: `cupboard`},
+		`use old key with old box`: {verb: `use`, object: `old key`, preposition: `with`, indirectObject: `old box`},
+		`north`:                    {verb: `go`, object: `north`},
+		`n`:                        {verb: `go`, object: `north`},
+		`go to s`:                  {verb: `go`, object: `south`},
+		`look at the cupboard`:     {verb: `look`, object: `cupboard`},
+		`l`:                        {verb: `look`},
+		`inspect reflection`:       {verb: `inspect`, object: `reflection`},
+		``:                         {},
+	}
+	for input, expectedCommand := range inputCommands {
+		command := dictionary.parseCommand(input)
+		expectedCommand.input = input
+		if command != expectedCommand {
+			t.Errorf("Expected %+v for '%s' but got %+v", expectedCommand, input, command)
+		}
+	}
+}
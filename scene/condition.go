@@ -0,0 +1,140 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the conditions which guard keywords and narrator lines in the scripts.
+package scene
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scriptCondition is written as one or more terms joined by '&', e.g. 'has:cupboardKey & !visited:Forest & coins >= 5'.
+//
+// All terms have to be met for the condition to be met.
+type scriptCondition struct {
+	source string
+	terms  []conditionTerm
+}
+
+// conditionTerm is a single check like 'has:cupboardKey' which can be negated with a leading '!'.
+//
+// A term can also compare a variable like 'coins >= 5', then kind is empty and operator and value are set.
+type conditionTerm struct {
+	isNegated bool
+	kind      string
+	name      string
+	operator  string
+	value     string
+}
+
+var (
+	conditionTermRegexp       = regexp.MustCompile(`^(!?)\s*(\w+):(\S+)$`)
+	conditionComparisonRegexp = regexp.MustCompile(`^(\w+)\s*(==|!=|>=|<=|>|<)\s*(\S+)$`)
+)
+
+// conditionKinds maps the known term kinds to the function checking them against the current game state.
+var conditionKinds = map[string]func(name string) bool{
+	// has:item is met if the player carries the item
+	`has`: func(name string) bool {
+		return globalPlayer.hasItem(name)
+	},
+	// flag:name is met if the variable has been set to something else than '', 'false', '0' or 'no'
+	`flag`: func(name string) bool {
+		return isTruthy(globalWorld.variables[name])
+	},
+	// visited:Scene is met if the player has entered the scene at least once
+	`visited`: func(name string) bool {
+		return getVisitCount(name) > 0
+	},
+}
+
+// parseCondition reads a condition like 'has:cupboardKey & !visited:Forest'.
+func parseCondition(source string) (*scriptCondition, error) {
+	condition := &scriptCondition{source: strings.TrimSpace(source)}
+
+	for _, termSource := range strings.Split(source, `&`) {
+		termSource = strings.TrimSpace(termSource)
+		if comparisonMatch := conditionComparisonRegexp.FindStringSubmatch(termSource); comparisonMatch != nil {
+			condition.terms = append(condition.terms, conditionTerm{
+				name:     comparisonMatch[1],
+				operator: comparisonMatch[2],
+				value:    comparisonMatch[3],
+			})
+			continue
+		}
+		termMatch := conditionTermRegexp.FindStringSubmatch(termSource)
+		if termMatch == nil {
+			return nil, fmt.Errorf("malformed condition '%s' (expected e.g. 'has:item', '!visited:Scene' or 'coins >= 5')",
+				termSource)
+		}
+		if _, isKnown := conditionKinds[termMatch[2]]; !isKnown {
+			return nil, fmt.Errorf("unknown condition '%s:' (known are 'has:', 'flag:' and 'visited:')", termMatch[2])
+		}
+		condition.terms = append(condition.terms, conditionTerm{
+			isNegated: termMatch[1] == `!`,
+			kind:      termMatch[2],
+			name:      termMatch[3],
+		})
+	}
+
+	return condition, nil
+}
+
+// isMet checks the condition against the current game state. A missing condition is always met.
+func (c *scriptCondition) isMet() bool {
+	if c == nil {
+		return true
+	}
+	for _, term := range c.terms {
+		if term.operator != `` {
+			if !term.compareVariable() {
+				return false
+			}
+			continue
+		}
+		if conditionKinds[term.kind](term.name) == term.isNegated {
+			return false
+		}
+	}
+	return true
+}
+
+// compareVariable compares numerically if both sides are numbers and as strings otherwise.
+func (term conditionTerm) compareVariable() bool {
+	variable := globalWorld.variables[term.name]
+
+	value, valueErr := strconv.Atoi(term.value)
+	if valueErr == nil && (variable == `` || isNumber(variable)) {
+		number := globalWorld.getNumber(term.name)
+		switch term.operator {
+		case `==`:
+			return number == value
+		case `!=`:
+			return number != value
+		case `>=`:
+			return number >= value
+		case `<=`:
+			return number <= value
+		case `>`:
+			return number > value
+		case `<`:
+			return number < value
+		}
+	}
+
+	switch term.operator {
+	case `==`:
+		return variable == term.value
+	case `!=`:
+		return variable != term.value
+	}
+	return false
+}
+
+func isNumber(str string) bool {
+	_, err := strconv.Atoi(str)
+	return err == nil
+}
@@ -0,0 +1,288 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file exports the story structure (sections, progress jumps and directions between scenes) as a graph.
+package scene
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/3ter/iMagine/fileio"
+)
+
+// GraphFormats contains the formats 'ExportGraph' can write.
+var GraphFormats = []string{`dot`, `mermaid`}
+
+// storyGraph contains every scene with its sections and the edges between them.
+type storyGraph struct {
+	scenes []*graphScene
+	edges  []graphEdge
+}
+
+type graphScene struct {
+	name     string
+	sections []*graphSection
+}
+
+// graphSection is a section of a scene's script.
+//
+// A dead end has no keyword jumping anywhere so the player can only leave it with 'go'.
+type graphSection struct {
+	name          string
+	isDeadEnd     bool
+	isUnreachable bool
+}
+
+// graphEdge is either a progress jump between sections or a direction between scenes.
+//
+// The ends are written like the progress targets, i.e. 'Scene#section' for sections and 'Scene' for scenes.
+type graphEdge struct {
+	from, to    string
+	label       string
+	isDirection bool
+}
+
+// readStoryGraph loads every scene folder inside contentDir like 'LintContent' does. The graph contains whatever could
+// be read, the problems of the script and map config files are returned with it.
+func readStoryGraph(contentDir string) (*storyGraph, ScriptErrorList, error) {
+	sceneNames, err := readSceneNames(contentDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(sceneNames)
+
+	var problems ScriptErrorList
+	addProblems := func(err error) {
+		if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+			problems = append(problems, errorList...)
+		}
+	}
+	scripts := make(map[string]*scriptFile)
+	mapConfigs := make(map[string]*MapConfig)
+	for _, sceneName := range sceneNames {
+		contentFiles, err := readSceneFolder(contentDir, sceneName)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, contentFile := range contentFiles {
+			if contentFile.language != `` {
+				continue
+			}
+			if contentFile.name == `script` && contentFile.extension == `md` {
+				scripts[sceneName], err = parseScript(contentFile.path, fileio.LoadFileToString(contentFile.path))
+				addProblems(err)
+			} else if contentFile.name == `mapConfig` && contentFile.extension == `json` {
+				var mapConfig MapConfig
+				addProblems(loadMapConfigFile(contentFile.path, &mapConfig))
+				mapConfigs[sceneName] = &mapConfig
+			}
+		}
+	}
+
+	graph := &storyGraph{}
+	for _, sceneName := range sceneNames {
+		scene := &graphScene{name: sceneName}
+		graph.scenes = append(graph.scenes, scene)
+
+		if parsed := scripts[sceneName]; parsed != nil {
+			reachableSections := getReachableSections(sceneName, parsed, getEntrySections(sceneName, parsed, scripts)...)
+			for _, section := range parsed.sections {
+				graphSection := &graphSection{
+					name:          section.name,
+					isDeadEnd:     true,
+					isUnreachable: !reachableSections[section],
+				}
+				scene.sections = append(scene.sections, graphSection)
+
+				for _, keyword := range section.keywords {
+					if keyword.progressTarget == `` {
+						continue
+					}
+					graphSection.isDeadEnd = false
+					targetSceneName, targetSection := splitProgressTarget(keyword.progressTarget)
+					if targetSceneName == `` {
+						targetSceneName = sceneName
+					}
+					label := keyword.keyword
+					if keyword.condition != nil {
+						label += ` ? ` + keyword.condition.source
+					}
+					graph.edges = append(graph.edges, graphEdge{
+						from:  sceneName + `#` + section.name,
+						to:    targetSceneName + `#` + targetSection,
+						label: label,
+					})
+				}
+			}
+		}
+
+		if mapConfig := mapConfigs[sceneName]; mapConfig != nil {
+			var directions []string
+			for direction := range mapConfig.Directions {
+				directions = append(directions, direction)
+			}
+			sort.Strings(directions)
+			for _, direction := range directions {
+				graph.edges = append(graph.edges, graphEdge{
+					from:        sceneName,
+					to:          mapConfig.Directions[direction],
+					label:       direction,
+					isDirection: true,
+				})
+			}
+		}
+	}
+	return graph, problems, nil
+}
+
+// getLabel returns the section name together with its markings.
+func (s *graphSection) getLabel() string {
+	label := s.name
+	if s.isDeadEnd {
+		label += ` (dead end)`
+	}
+	if s.isUnreachable {
+		label += ` (unreachable)`
+	}
+	return label
+}
+
+// ExportGraph returns the story structure of the content inside contentDir in one of the 'GraphFormats'.
+//
+// Every scene is a cluster containing its sections. Progress jumps are solid edges labelled with the keyword, the
+// directions of the map configs are dashed edges between the scenes. Dead ends and unreachable sections are marked.
+//
+// The returned problems are the ones of the files which could only be read partly, the graph shows what could be read.
+func ExportGraph(contentDir, format string) (string, ScriptErrorList, error) {
+	graph, problems, err := readStoryGraph(contentDir)
+	if err != nil {
+		return ``, nil, err
+	}
+	switch format {
+	case `dot`:
+		return graph.toDot(), problems, nil
+	case `mermaid`:
+		return graph.toMermaid(), problems, nil
+	}
+	return ``, problems, fmt.Errorf("unknown graph format '%s' (known are %s)", format, strings.Join(GraphFormats, `, `))
+}
+
+// getDotID quotes a node or cluster name for Graphviz.
+func getDotID(name string) string {
+	return `"` + strings.Replace(name, `"`, `\"`, -1) + `"`
+}
+
+// getSceneNode returns the node which represents the scene for the direction edges.
+func (g *storyGraph) getSceneNode(sceneName string) string {
+	for _, scene := range g.scenes {
+		if scene.name != sceneName {
+			continue
+		}
+		for _, section := range scene.sections {
+			if section.name == `beginning` {
+				return sceneName + `#beginning`
+			}
+		}
+	}
+	return sceneName
+}
+
+// toDot writes the graph in the Graphviz DOT language, e.g. for 'dot -Tsvg'.
+func (g *storyGraph) toDot() string {
+	var dot strings.Builder
+	dot.WriteString("digraph story {\n")
+	dot.WriteString("\tcompound=true;\n")
+	dot.WriteString("\tnode [shape=box, style=rounded];\n")
+
+	for _, scene := range g.scenes {
+		dot.WriteString("\n\tsubgraph " + getDotID(`cluster_`+scene.name) + " {\n")
+		dot.WriteString("\t\tlabel=" + getDotID(scene.name) + ";\n")
+		if g.getSceneNode(scene.name) == scene.name {
+			dot.WriteString("\t\t" + getDotID(scene.name) + " [label=\"(no beginning)\", shape=plaintext];\n")
+		}
+		for _, section := range scene.sections {
+			attributes := "label=" + getDotID(section.getLabel())
+			if section.isDeadEnd {
+				attributes += ", color=red"
+			}
+			if section.isUnreachable {
+				attributes += ", style=\"rounded,dashed\", fontcolor=gray"
+			}
+			dot.WriteString("\t\t" + getDotID(scene.name+`#`+section.name) + " [" + attributes + "];\n")
+		}
+		dot.WriteString("\t}\n")
+	}
+
+	dot.WriteString("\n")
+	for _, edge := range g.edges {
+		if !edge.isDirection {
+			dot.WriteString("\t" + getDotID(edge.from) + " -> " + getDotID(edge.to) +
+				" [label=" + getDotID(edge.label) + "];\n")
+			continue
+		}
+		dot.WriteString("\t" + getDotID(g.getSceneNode(edge.from)) + " -> " + getDotID(g.getSceneNode(edge.to)) +
+			" [label=" + getDotID(edge.label) + ", style=dashed, ltail=" + getDotID(`cluster_`+edge.from) +
+			", lhead=" + getDotID(`cluster_`+edge.to) + "];\n")
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
+
+var mermaidIDRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// getMermaidID turns a name like 'Beach#get_compass' into a node id like 'Beach_get_compass'.
+func getMermaidID(name string) string {
+	return mermaidIDRegexp.ReplaceAllString(name, `_`)
+}
+
+// getMermaidText escapes the characters which would end a label.
+func getMermaidText(text string) string {
+	return strings.NewReplacer(`"`, `#quot;`, `|`, `#124;`).Replace(text)
+}
+
+// toMermaid writes the graph as a Mermaid flowchart which GitHub and GitLab render in markdown files and pull requests.
+func (g *storyGraph) toMermaid() string {
+	var mermaid strings.Builder
+	mermaid.WriteString("flowchart TD\n")
+
+	var deadEnds, unreachableSections []string
+	for _, scene := range g.scenes {
+		mermaid.WriteString("\tsubgraph " + getMermaidID(scene.name) + "[\"" + getMermaidText(scene.name) + "\"]\n")
+		if len(scene.sections) == 0 {
+			mermaid.WriteString("\t\t" + getMermaidID(scene.name+`#`) + "[\"(no script)\"]\n")
+		}
+		for _, section := range scene.sections {
+			id := getMermaidID(scene.name + `#` + section.name)
+			mermaid.WriteString("\t\t" + id + "[\"" + getMermaidText(section.getLabel()) + "\"]\n")
+			if section.isDeadEnd {
+				deadEnds = append(deadEnds, id)
+			}
+			if section.isUnreachable {
+				unreachableSections = append(unreachableSections, id)
+			}
+		}
+		mermaid.WriteString("\tend\n")
+	}
+
+	for _, edge := range g.edges {
+		arrow := ` -->|`
+		if edge.isDirection {
+			arrow = ` -.->|`
+		}
+		mermaid.WriteString("\t" + getMermaidID(edge.from) + arrow + "\"" + getMermaidText(edge.label) + "\"| " +
+			getMermaidID(edge.to) + "\n")
+	}
+
+	mermaid.WriteString("\tclassDef deadEnd stroke:#c00,stroke-width:2px\n")
+	mermaid.WriteString("\tclassDef unreachable stroke-dasharray:5 5,color:#999\n")
+	if len(deadEnds) > 0 {
+		mermaid.WriteString("\tclass " + strings.Join(deadEnds, `,`) + " deadEnd\n")
+	}
+	if len(unreachableSections) > 0 {
+		mermaid.WriteString("\tclass " + strings.Join(unreachableSections, `,`) + " unreachable\n")
+	}
+	return mermaid.String()
+}
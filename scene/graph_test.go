@@ -0,0 +1,82 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportGraph(t *testing.T) {
+	contentDir, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+
+	files := map[string]string{
+		"Island/script.md": "# beginning\n" +
+			"`(Swim) > Sea#beginning`\n" +
+			"\n" +
+			"`(Sleep) ? flag:tired > dream`\n" +
+			"\n" +
+			"# dream\n" +
+			"You dream.\n" +
+			"\n" +
+			"# forgotten\n" +
+			"Nobody comes here.\n",
+		"Island/mapConfig.json": `{"directions": {"north": "Sea"}}`,
+		"Sea/script.md":         "# beginning\nWater everywhere.\n",
+	}
+	for fileName, content := range files {
+		os.MkdirAll(filepath.Dir(contentDir+fileName), 0755)
+		if err := ioutil.WriteFile(contentDir+fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedLines := map[string][]string{
+		`dot`: {
+			`"Island#dream" [label="dream (dead end)", color=red];`,
+			`"Island#forgotten" [label="forgotten (dead end) (unreachable)", color=red, style="rounded,dashed", fontcolor=gray];`,
+			`"Island#beginning" -> "Sea#beginning" [label="Swim"];`,
+			`"Island#beginning" -> "Island#dream" [label="Sleep ? flag:tired"];`,
+			`"Island#beginning" -> "Sea#beginning" [label="north", style=dashed, ltail="cluster_Island", lhead="cluster_Sea"];`,
+		},
+		`mermaid`: {
+			`subgraph Island["Island"]`,
+			`Island_beginning -->|"Swim"| Sea_beginning`,
+			`Island -.->|"north"| Sea`,
+			`class Island_dream,Island_forgotten,Sea_beginning deadEnd`,
+			`class Island_forgotten unreachable`,
+		},
+	}
+	for format, lines := range expectedLines {
+		graph, problems, err := ExportGraph(contentDir, format)
+		if err != nil || len(problems) > 0 {
+			t.Fatal(err, problems)
+		}
+		for _, line := range lines {
+			if !strings.Contains(graph, "\t"+line+"\n") {
+				t.Errorf("Expected the %s graph to contain %q:\n%s", format, line, graph)
+			}
+		}
+	}
+
+	if _, _, err := ExportGraph(contentDir, `svg`); err == nil {
+		t.Fatalf("Expected an error for an unknown format")
+	}
+
+	// The graph is still written if files have problems, the problems are returned with it
+	ioutil.WriteFile(contentDir+"Island/mapConfig.json", []byte(`{"directions": {"north": "Sea"},}`), 0644)
+	ioutil.WriteFile(contentDir+"Sea/script.md", []byte("Water everywhere.\n"), 0644)
+	graph, problems, err := ExportGraph(contentDir, `dot`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 || !strings.Contains(graph, `"Island#dream"`) {
+		t.Errorf("Expected the graph with the problems of both files but got %v:\n%s", problems, graph)
+	}
+}
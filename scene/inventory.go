@@ -0,0 +1,154 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the items the player takes from the scenes, carries around and drops again.
+package scene
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// giveDirective is written as `[Give: cupboardKey]` and puts the object into the player's inventory
+const giveDirective = `Give`
+
+var camelCaseWordRegexp = regexp.MustCompile(`[A-Z]?[a-z0-9]+|[A-Z]+`)
+
+// getObjectName returns how the player refers to an object, its 'name' or else the words of the object's file name
+// (e.g. 'old cupboard' for 'oldCupboard.json').
+func getObjectName(objectName string, object *sceneObject) string {
+	if object.Name != `` {
+		return strings.ToLower(object.Name)
+	}
+	return strings.ToLower(strings.Join(camelCaseWordRegexp.FindAllString(objectName, -1), ` `))
+}
+
+// isItem returns whether the object can be carried. Objects with a 'name' are items, the others (e.g. furniture) stay
+// in their scene.
+func isItem(object *sceneObject) bool {
+	return object.Name != ``
+}
+
+// findObject returns the name of the object which the player refers to with the command's object.
+func findObject(objects map[string]*sceneObject, commandObject string) string {
+	for objectName, object := range objects {
+		if commandObject == getObjectName(objectName, object) || commandObject == strings.ToLower(objectName) {
+			return objectName
+		}
+	}
+	return ``
+}
+
+// addItem puts the object into the player's inventory.
+func (p *Player) addItem(objectName string, object *sceneObject) {
+	if p.hasItem(objectName) {
+		return
+	}
+	if p.items == nil {
+		p.items = make(map[string]*sceneObject)
+	}
+	p.items[objectName] = object
+}
+
+// removeItem takes the object out of the player's inventory and returns it.
+func (p *Player) removeItem(objectName string) *sceneObject {
+	object := p.items[objectName]
+	delete(p.items, objectName)
+	return object
+}
+
+// getInventoryText lists the carried items in alphabetical order.
+func (p *Player) getInventoryText() string {
+	if len(p.items) == 0 {
+		return translate("You don't carry anything.")
+	}
+	var itemNames []string
+	for objectName, object := range p.items {
+		itemNames = append(itemNames, getObjectName(objectName, object))
+	}
+	sort.Strings(itemNames)
+	return fmt.Sprintf(translate("You carry: %s."), strings.Join(itemNames, `, `))
+}
+
+// handleItemActions executes the built-in verbs 'take', 'drop' and 'inventory'. It returns false if the command
+// doesn't refer to an item so script keywords close to the input get their chance.
+func (s *Scene) handleItemActions(command playerCommand) bool {
+	switch command.verb {
+	case `inventory`:
+		globalNarrator.setTextLetterByLetter(globalPlayer.getInventoryText(), s)
+	case `take`:
+		if command.object == `` {
+			globalNarrator.setTextLetterByLetter(translate("What do you want to take?"), s)
+			return true
+		}
+		if objectName := findObject(globalPlayer.items, command.object); objectName != `` {
+			globalNarrator.setTextLetterByLetter(fmt.Sprintf(translate("You already carry the %s."),
+				getObjectName(objectName, globalPlayer.items[objectName])), s)
+			return true
+		}
+		objectName := findObject(s.objects, command.object)
+		if objectName == `` {
+			return false
+		}
+		object := s.objects[objectName]
+		if !isItem(object) {
+			globalNarrator.setTextLetterByLetter(fmt.Sprintf(translate("You can't take the %s."),
+				getObjectName(objectName, object)), s)
+			return true
+		}
+		delete(s.objects, objectName)
+		globalPlayer.addItem(objectName, object)
+		globalNarrator.setTextLetterByLetter(fmt.Sprintf(translate("You take the %s."),
+			getObjectName(objectName, object)), s)
+	case `drop`:
+		if command.object == `` {
+			globalNarrator.setTextLetterByLetter(translate("What do you want to drop?"), s)
+			return true
+		}
+		objectName := findObject(globalPlayer.items, command.object)
+		if objectName == `` {
+			return false
+		}
+		object := globalPlayer.removeItem(objectName)
+		if s.objects == nil {
+			s.objects = make(map[string]*sceneObject)
+		}
+		s.objects[objectName] = object
+		globalNarrator.setTextLetterByLetter(fmt.Sprintf(translate("You drop the %s."),
+			getObjectName(objectName, object)), s)
+	}
+	return true
+}
+
+// giveItem executes `[Give: objectName]`. The object is taken from the current scene or, if it isn't there, from
+// any other scene.
+func giveItem(objectName string) {
+	if globalPlayer.hasItem(objectName) {
+		return
+	}
+
+	sceneNames := []string{GlobalCurrentScene}
+	var otherSceneNames []string
+	for sceneName := range GlobalScenes {
+		if sceneName != GlobalCurrentScene {
+			otherSceneNames = append(otherSceneNames, sceneName)
+		}
+	}
+	sort.Strings(otherSceneNames)
+	for _, sceneName := range append(sceneNames, otherSceneNames...) {
+		s := GlobalScenes[sceneName]
+		if s == nil {
+			continue
+		}
+		if object, isFound := s.objects[objectName]; isFound {
+			delete(s.objects, objectName)
+			globalPlayer.addItem(objectName, object)
+			return
+		}
+	}
+	log.Printf("[%s: %s] gives an object which doesn't exist in any scene", giveDirective, objectName)
+	globalPlayer.addItem(objectName, &sceneObject{})
+}
@@ -0,0 +1,69 @@
+package scene
+
+import (
+	"testing"
+)
+
+func TestInventory(t *testing.T) {
+	previousScenes, previousCurrentScene := GlobalScenes, GlobalCurrentScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene = previousScenes, previousCurrentScene
+		globalPlayer.items = nil
+	}()
+	globalPlayer.items = nil
+
+	beach := getSceneObjectWithDefaults()
+	beach.Name = `Beach`
+	beach.objects = map[string]*sceneObject{
+		`cupboardKey`: {Name: `Old cupboard key`, Description: `An old key.`},
+		`oldCupboard`: {Attributes: map[string]string{`lock_state`: `locked`}},
+	}
+	beach.script.parsed, beach.script.parseErr = parseScript(`Beach/script.md`, "# beginning\n"+
+		"`(Dig)`\n"+
+		"`[Give: shell]`\n"+
+		"You find a shell.\n")
+	forest := getSceneObjectWithDefaults()
+	forest.Name = `Forest`
+	forest.objects = map[string]*sceneObject{`shell`: {Name: `shell`}}
+	if beach.script.parseErr != nil {
+		t.Fatal(beach.script.parseErr)
+	}
+	GlobalScenes = map[string]*Scene{`Beach`: beach, `Forest`: forest}
+	GlobalCurrentScene = `Beach`
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scene        *Scene
+		input        string
+		expectedText string
+	}{
+		{beach, `inventory`, `You don't carry anything.`},
+		{beach, `take`, `What do you want to take?`},
+		{beach, `take old cupboard`, `You can't take the old cupboard.`},
+		{beach, `take the old cupboard key`, `You take the old cupboard key.`},
+		{beach, `take old cupboard key`, `You already carry the old cupboard key.`},
+		{beach, `dig`, `You find a shell.`},
+		{beach, `i`, `You carry: old cupboard key, shell.`},
+		{forest, `drop old cupboard key`, `You drop the old cupboard key.`},
+		{forest, `inventory`, `You carry: shell.`},
+	}
+	for _, test := range tests {
+		test.scene.handlePlayerCommand(test.input)
+		if globalNarrator.currentTextString != test.expectedText {
+			t.Errorf("Expected '%s' to be answered with '%s' but got '%s'", test.input, test.expectedText,
+				globalNarrator.currentTextString)
+		}
+	}
+
+	if _, isInBeach := beach.objects[`cupboardKey`]; isInBeach {
+		t.Errorf("Expected the taken key to have left the beach")
+	}
+	if _, isInForest := forest.objects[`shell`]; isInForest {
+		t.Errorf("Expected the given shell to have left the forest")
+	}
+	if key := forest.objects[`cupboardKey`]; key == nil || key.Description != `An old key.` {
+		t.Errorf("Expected the dropped key to be in the forest but got %v", forest.objects)
+	}
+}
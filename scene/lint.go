@@ -0,0 +1,293 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file checks the scene content for problems that would otherwise only show up while playing.
+package scene
+
+import (
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/3ter/iMagine/fileio"
+)
+
+var (
+	spanOpenRegexp  = regexp.MustCompile(`<span(\s[^>]*)?>`)
+	spanCloseRegexp = regexp.MustCompile(`</span\s*>`)
+)
+
+// LintContent loads every scene folder inside contentDir like 'LoadFilesToSceneMap' does and returns all problems
+// found, sorted by file and line.
+//
+// Nothing is played or drawn so this can run without a window.
+func LintContent(contentDir string) ScriptErrorList {
+	var problems ScriptErrorList
+
+	sceneNames, err := readSceneNames(contentDir)
+	if err != nil {
+		return ScriptErrorList{{File: contentDir, Msg: err.Error()}}
+	}
+	if _, err := loadVerbDictionary(contentDir + VerbDictionaryFile); err != nil && !os.IsNotExist(err) {
+		problems = append(problems, &ScriptError{File: contentDir + VerbDictionaryFile, Msg: err.Error()})
+	}
+
+	sceneNameSet := make(map[string]bool)
+	for _, sceneName := range sceneNames {
+		sceneNameSet[sceneName] = true
+	}
+
+	// All scripts are parsed first because keywords can jump into the sections of other scenes
+	scripts := make(map[string]*scriptFile)
+	localizedScripts := make(map[string][]*scriptFile)
+	for _, sceneName := range sceneNames {
+		contentFiles, err := readSceneFolder(contentDir, sceneName)
+		if err != nil {
+			problems = append(problems, &ScriptError{File: contentDir + sceneName, Msg: err.Error()})
+			continue
+		}
+		// Localized object files are checked on top of the default ones like they are loaded
+		objects := make(map[string]*sceneObject)
+		for _, contentFile := range contentFiles {
+			if contentFile.name == `script` && contentFile.extension == `md` {
+				parsed, err := parseScript(contentFile.path, fileio.LoadFileToString(contentFile.path))
+				if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+					problems = append(problems, errorList...)
+				}
+				if contentFile.language != `` {
+					localizedScripts[sceneName] = append(localizedScripts[sceneName], parsed)
+				} else {
+					scripts[sceneName] = parsed
+				}
+			} else if contentFile.name == `mapConfig` && contentFile.extension == `json` {
+				problems = append(problems, lintMapConfigFile(contentFile.path, sceneNameSet)...)
+			} else if contentFile.extension == `json` {
+				if objects[contentFile.name] == nil {
+					objects[contentFile.name] = &sceneObject{}
+				}
+				err := loadObjectFile(contentFile.path, objects[contentFile.name])
+				if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+					problems = append(problems, errorList...)
+				}
+			}
+		}
+	}
+
+	for _, sceneName := range sceneNames {
+		if parsed := scripts[sceneName]; parsed != nil {
+			problems = append(problems, lintScript(sceneName, parsed, scripts)...)
+		}
+		for _, localized := range localizedScripts[sceneName] {
+			problems = append(problems, lintLocalizedScript(localized, scripts[sceneName])...)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// lintScript checks the script of a scene, scripts contains the scripts of all scenes by scene name.
+func lintScript(sceneName string, parsed *scriptFile, scripts map[string]*scriptFile) ScriptErrorList {
+	var problems ScriptErrorList
+
+	if parsed.sectionMap[`beginning`] == nil {
+		problems = append(problems, &ScriptError{File: parsed.filePath, Line: 1, Msg: "there is no '# beginning' section"})
+	}
+	entrySections := getEntrySections(sceneName, parsed, scripts)
+
+	for _, section := range parsed.sections {
+		for _, line := range section.lines {
+			problems = append(problems, lintNarratorLine(line)...)
+		}
+		for _, keyword := range section.keywords {
+			for _, line := range keyword.allLines() {
+				problems = append(problems, lintNarratorLine(line)...)
+			}
+			if keyword.progressTarget == `` {
+				continue
+			}
+			if msg := checkProgressTarget(keyword.progressTarget, sceneName, scripts); msg != `` {
+				problems = append(problems, &ScriptError{
+					File: keyword.pos.file,
+					Line: keyword.pos.line,
+					Msg:  "'(" + keyword.keyword + ")' jumps to " + msg,
+				})
+			}
+		}
+	}
+
+	if len(entrySections) > 0 {
+		reachableSections := getReachableSections(sceneName, parsed, entrySections...)
+		for _, section := range parsed.sections {
+			if !reachableSections[section] {
+				problems = append(problems, &ScriptError{
+					File: parsed.filePath,
+					Line: section.pos.line,
+					Msg:  "section '# " + section.name + "' can't be reached from '# beginning'",
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// lintLocalizedScript reports sections which don't replace a section of the default script, e.g. because the section
+// has been renamed in the default script only.
+func lintLocalizedScript(localized, defaultScript *scriptFile) ScriptErrorList {
+	var problems ScriptErrorList
+	for _, section := range localized.sections {
+		if defaultScript == nil || defaultScript.sectionMap[section.name] == nil {
+			problems = append(problems, &ScriptError{
+				File: localized.filePath,
+				Line: section.pos.line,
+				Msg:  "section '# " + section.name + "' doesn't exist in the default script",
+			})
+		}
+		for _, line := range section.lines {
+			problems = append(problems, lintNarratorLine(line)...)
+		}
+		for _, keyword := range section.keywords {
+			for _, line := range keyword.allLines() {
+				problems = append(problems, lintNarratorLine(line)...)
+			}
+		}
+	}
+	return problems
+}
+
+// checkProgressTarget returns a description of what's wrong with the jump target or an empty string.
+func checkProgressTarget(target, sceneName string, scripts map[string]*scriptFile) string {
+	targetSceneName, targetSection := splitProgressTarget(target)
+	if targetSceneName == `` {
+		targetSceneName = sceneName
+	}
+	targetScript, hasScript := scripts[targetSceneName]
+	if !hasScript {
+		return "the scene '" + targetSceneName + "' which has no script"
+	}
+	if targetScript.sectionMap[targetSection] == nil {
+		return "'# " + targetSection + "' which doesn't exist in '" + targetScript.filePath + "'"
+	}
+	return ``
+}
+
+// getEntrySections returns the sections a scene can be entered at: '# beginning' and the sections other scenes jump to.
+func getEntrySections(sceneName string, parsed *scriptFile, scripts map[string]*scriptFile) []*scriptSection {
+	var entrySections []*scriptSection
+	if beginning := parsed.sectionMap[`beginning`]; beginning != nil {
+		entrySections = append(entrySections, beginning)
+	}
+	for otherSceneName, otherScript := range scripts {
+		for _, section := range otherScript.sections {
+			for _, keyword := range section.keywords {
+				targetSceneName, targetSection := splitProgressTarget(keyword.progressTarget)
+				if targetSceneName == sceneName && otherSceneName != sceneName && parsed.sectionMap[targetSection] != nil {
+					entrySections = append(entrySections, parsed.sectionMap[targetSection])
+				}
+			}
+		}
+	}
+	return entrySections
+}
+
+// getReachableSections follows the progress jumps inside of the scene's script starting from the given sections.
+func getReachableSections(sceneName string, parsed *scriptFile, starts ...*scriptSection) map[*scriptSection]bool {
+	reachableSections := make(map[*scriptSection]bool)
+	for _, start := range starts {
+		reachableSections[start] = true
+	}
+	sectionsToVisit := append([]*scriptSection{}, starts...)
+	for len(sectionsToVisit) > 0 {
+		section := sectionsToVisit[0]
+		sectionsToVisit = sectionsToVisit[1:]
+		for _, keyword := range section.keywords {
+			targetSceneName, targetSection := splitProgressTarget(keyword.progressTarget)
+			if targetSceneName != `` && targetSceneName != sceneName {
+				continue
+			}
+			target := parsed.sectionMap[targetSection]
+			if target != nil && !reachableSections[target] {
+				reachableSections[target] = true
+				sectionsToVisit = append(sectionsToVisit, target)
+			}
+		}
+	}
+	return reachableSections
+}
+
+func lintNarratorLine(line *narratorLine) ScriptErrorList {
+	var problems ScriptErrorList
+
+	for _, ambienceCmd := range line.ambience {
+		if ambienceCmd.kind != `Audio` {
+			continue
+		}
+		if _, err := os.Stat(AssetsDir + ambienceCmd.argument); err != nil {
+			problems = append(problems, &ScriptError{
+				File: ambienceCmd.pos.file,
+				Line: ambienceCmd.pos.line,
+				Msg:  "audio file '" + ambienceCmd.argument + "' doesn't exist in '" + AssetsDir + "'",
+			})
+		}
+	}
+
+	if msg := checkSpanBalance(line.text); msg != `` {
+		problems = append(problems, &ScriptError{File: line.pos.file, Line: line.pos.line, Msg: msg})
+	}
+
+	return problems
+}
+
+// checkSpanBalance returns a description of the first unbalanced <span> tag or an empty string.
+func checkSpanBalance(text string) string {
+	openIndices := spanOpenRegexp.FindAllStringIndex(text, -1)
+	closeIndices := spanCloseRegexp.FindAllStringIndex(text, -1)
+
+	depth := 0
+	for openIdx, closeIdx := 0, 0; openIdx < len(openIndices) || closeIdx < len(closeIndices); {
+		if closeIdx >= len(closeIndices) ||
+			(openIdx < len(openIndices) && openIndices[openIdx][0] < closeIndices[closeIdx][0]) {
+			depth++
+			openIdx++
+			continue
+		}
+		if depth == 0 {
+			return "'</span>' without an opening '<span>'"
+		}
+		depth--
+		closeIdx++
+	}
+	if depth > 0 {
+		return "'<span>' without a closing '</span>'"
+	}
+	return ``
+}
+
+func lintMapConfigFile(filePath string, sceneNameSet map[string]bool) ScriptErrorList {
+	var problems ScriptErrorList
+	var mapConfig MapConfig
+	if errorList, isErrorList := loadMapConfigFile(filePath, &mapConfig).(ScriptErrorList); isErrorList {
+		problems = append(problems, errorList...)
+	}
+
+	var directions []string
+	for direction := range mapConfig.Directions {
+		directions = append(directions, direction)
+	}
+	sort.Strings(directions)
+	for _, direction := range directions {
+		if sceneName := mapConfig.Directions[direction]; !sceneNameSet[sceneName] {
+			problems = append(problems, &ScriptError{
+				File: filePath,
+				Msg:  "direction '" + direction + "' points at the missing scene folder '" + sceneName + "'",
+			})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,64 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintContent(t *testing.T) {
+	if problems := LintContent(ContentDir); len(problems) > 0 {
+		t.Fatalf("The shipped content has problems:\n%v", problems)
+	}
+}
+
+func TestLintContentFindsProblems(t *testing.T) {
+	contentDir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+
+	files := map[string]string{
+		"Island/script.md": "# start\n" +
+			"`[Audio: Missing.ogg]`\n" +
+			"\n" +
+			"The <span style=\"color:red\">sun is setting.\n" +
+			"\n" +
+			"`(Swim) > nowhere`\n" +
+			"`(Sail) > Beach#harbour`\n" +
+			"\n" +
+			"# lost\n" +
+			"Nobody comes here.\n",
+		"Island/mapConfig.json": `{"directions": {"north": "Beach", "south": "Island"}}`,
+		"Island/script.de.md":   "# sunset\nDie Sonne geht unter.\n",
+	}
+	for fileName, content := range files {
+		os.MkdirAll(filepath.Dir(contentDir+fileName), 0755)
+		if err := ioutil.WriteFile(contentDir+fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedProblems := []string{
+		`Island/mapConfig.json: direction 'north' points at the missing scene folder 'Beach'`,
+		`Island/script.de.md:1: section '# sunset' doesn't exist in the default script`,
+		`Island/script.md:1: there is no '# beginning' section`,
+		`Island/script.md:2: audio file 'Missing.ogg' doesn't exist`,
+		`Island/script.md:4: '<span>' without a closing '</span>'`,
+		`Island/script.md:6: '(Swim)' jumps to '# nowhere' which doesn't exist`,
+		`Island/script.md:7: '(Sail)' jumps to the scene 'Beach' which has no script`,
+	}
+	problems := LintContent(contentDir)
+	if len(problems) != len(expectedProblems) {
+		t.Fatalf("Expected %d problems but got:\n%v", len(expectedProblems), problems)
+	}
+	for idx, expectedProblem := range expectedProblems {
+		if !strings.HasPrefix(problems[idx].Error(), contentDir+expectedProblem) {
+			t.Errorf("Expected problem starting with %q but got %q", expectedProblem, problems[idx].Error())
+		}
+	}
+}
@@ -5,9 +5,10 @@
 package scene
 
 import (
-	"encoding/json"
 	"io/ioutil"
+	"log"
 	"regexp"
+	"sort"
 
 	"github.com/3ter/iMagine/fileio"
 )
@@ -15,7 +16,13 @@ import (
 // ContentDir publishes the directory where its files are stored
 const ContentDir = `../scene/content/`
 
-var specialScenes = [2]string{`Demo`, `MainMenu`}
+// AssetsDir is the directory containing fonts, shaders and the audio files used by '[Audio: ...]' directives
+const AssetsDir = `../assets/`
+
+// SharedContentFolder contains text which scripts pull in with '[Include: Common/file.md#section]', it isn't a scene.
+const SharedContentFolder = `Common`
+
+var specialScenes = [3]string{`Demo`, `MainMenu`, `LoadMenu`}
 
 // GlobalScenes maps scene identifiers (e.g. 'Beach') to their respective scene object
 var GlobalScenes map[string]*Scene
@@ -33,27 +40,89 @@ type MapConfig struct {
 	// Directions maps north, east, south and west to their respective scene names
 	Directions map[string]string
 	Look       string
+	// Fallback contains lines of which one is picked at random to answer commands nobody understood.
+	// '{input}' is replaced with the player's input.
+	Fallback []string
 	// Number of times this scene has been entered
 	Visited int
 }
 
-func (s *Scene) loadMapConfig(filename string) {
-	jsonBytes := fileio.LoadFileToBytes(filename)
+// loadMapConfig reads a map config file, a localized map config is loaded on top of the default one so that only the
+// translated entries have to be written. The entries of an invalid file are skipped and its problems returned.
+func (s *Scene) loadMapConfig(filename string) error {
+	if s.mapConfig == nil {
+		s.mapConfig = &MapConfig{}
+	}
+	return loadMapConfigFile(filename, s.mapConfig)
+}
 
-	json.Unmarshal(jsonBytes, &s.mapConfig)
+// loadObject reads an object file, a localized object file is loaded on top of the default one. The object's
+// 'actions' and 'conditions' are executed in 'handleObjectActions'.
+func (s *Scene) loadObject(filename string, objectName string) error {
+	object := s.objects[objectName]
+	if object == nil {
+		object = &sceneObject{}
+	}
+	err := loadObjectFile(filename, object)
+	s.objects[objectName] = object
+	return err
 }
 
-func (s *Scene) loadObject(filename string, objectName string) {
-	jsonBytes := fileio.LoadFileToBytes(filename)
+// loadScript reads and parses the script file. Parse errors are logged and kept for when the script is played.
+func (s *Scene) loadScript(filename string) {
+	s.script.filePath = filename
+	s.script.fileContent = fileio.LoadFileToString(filename)
 
-	var objectData map[string]interface{}
-	json.Unmarshal(jsonBytes, &objectData)
+	s.script.parsed, s.script.parseErr = parseScript(filename, s.script.fileContent)
+	if s.script.parseErr != nil {
+		log.Println(s.script.parseErr)
+	}
+}
 
-	s.objects[objectName] = objectData
+// loadLocalizedScript replaces the sections of the default script with the ones of the localized script file.
+//
+// Sections which haven't been translated stay in the default language.
+func (s *Scene) loadLocalizedScript(filename string) {
+	localized, err := parseScript(filename, fileio.LoadFileToString(filename))
+	if err != nil {
+		log.Println(err)
+		if s.script.parseErr == nil {
+			s.script.parseErr = err
+		}
+	}
 
-	// TODO: I have no complete concept of how to deal with the unstructured data I've got here now.
-	// Data needs to be cast: s.objects[objectName][`id`].(string)
-	// I suppose we need to try and access certain keys in the map and determine the actions from there.
+	if s.script.parsed == nil {
+		s.script.filePath = filename
+		s.script.parsed = localized
+		return
+	}
+	s.script.parsed = mergeLocalizedScript(s.script.parsed, localized)
+}
+
+// mergeLocalizedScript returns a script with the sections of the default script in their order, each replaced by the
+// localized section of the same name if there is one. Sections only the localized script has are added at the end.
+func mergeLocalizedScript(defaultScript, localized *scriptFile) *scriptFile {
+	merged := &scriptFile{
+		filePath:   defaultScript.filePath,
+		sectionMap: make(map[string]*scriptSection),
+	}
+	addSection := func(section *scriptSection) {
+		merged.sections = append(merged.sections, section)
+		merged.sectionMap[section.name] = section
+	}
+
+	for _, section := range defaultScript.sections {
+		if localizedSection, isTranslated := localized.sectionMap[section.name]; isTranslated {
+			section = localizedSection
+		}
+		addSection(section)
+	}
+	for _, section := range localized.sections {
+		if _, isAdded := merged.sectionMap[section.name]; !isAdded {
+			addSection(section)
+		}
+	}
+	return merged
 }
 
 // isTestFile is a helper to skip go test files when looking for scene files
@@ -62,6 +131,64 @@ func isTestFile(filename string) bool {
 	return matchTestFile.MatchString(filename)
 }
 
+// sceneContentFile is a file inside a scene folder which is loaded into the scene.
+type sceneContentFile struct {
+	path string
+	name string
+	// language is empty for the default files and e.g. 'de' for 'script.de.md'
+	language  string
+	extension string
+}
+
+var contentFileFilter = regexp.MustCompile(`^(\w+)(?:\.([a-z]{2}))?\.(md|json)$`)
+
+// readSceneNames returns the names of all scene folders inside the content directory.
+func readSceneNames(contentDir string) ([]string, error) {
+	contentFolders, err := ioutil.ReadDir(contentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sceneNames []string
+	for _, contentFolder := range contentFolders {
+		if contentFolder.IsDir() && contentFolder.Name() != SharedContentFolder {
+			sceneNames = append(sceneNames, contentFolder.Name())
+		}
+	}
+	return sceneNames, nil
+}
+
+// readSceneFolder returns the script, map config and object files of a scene folder, the files in the default language
+// first.
+func readSceneFolder(contentDir, sceneName string) ([]sceneContentFile, error) {
+	contentFiles, err := ioutil.ReadDir(contentDir + sceneName)
+	if err != nil {
+		return nil, err
+	}
+
+	var sceneContentFiles []sceneContentFile
+	for _, contentFile := range contentFiles {
+		if isTestFile(contentFile.Name()) {
+			continue
+		}
+
+		fileMatchSlice := contentFileFilter.FindStringSubmatch(contentFile.Name())
+		if len(fileMatchSlice) == 4 {
+			sceneContentFiles = append(sceneContentFiles, sceneContentFile{
+				path:      contentDir + sceneName + "/" + fileMatchSlice[0],
+				name:      fileMatchSlice[1],
+				language:  fileMatchSlice[2],
+				extension: fileMatchSlice[3],
+			})
+		}
+	}
+	// Localized files are loaded on top of the default ones
+	sort.SliceStable(sceneContentFiles, func(i, j int) bool {
+		return sceneContentFiles[i].language == `` && sceneContentFiles[j].language != ``
+	})
+	return sceneContentFiles, nil
+}
+
 func buildSceneFromFolder(foldername string) {
 	sceneName := foldername
 
@@ -71,6 +198,15 @@ func buildSceneFromFolder(foldername string) {
 	}
 }
 
+func isSpecialScene(sceneName string) bool {
+	for _, specialScene := range specialScenes {
+		if sceneName == specialScene {
+			return true
+		}
+	}
+	return false
+}
+
 func addSpecialScenes() {
 	for _, specialScene := range specialScenes {
 		if GlobalScenes[specialScene] == nil {
@@ -80,6 +216,8 @@ func addSpecialScenes() {
 				GlobalScenes[`Demo`].initDemo()
 			} else if specialScene == `MainMenu` {
 				GlobalScenes[`MainMenu`].initMainMenu()
+			} else if specialScene == `LoadMenu` {
+				GlobalScenes[`LoadMenu`].initLoadMenu()
 			}
 		} else {
 			panic("Scene with name " + specialScene + " has been overwritten!")
@@ -90,55 +228,89 @@ func addSpecialScenes() {
 // LoadFilesToSceneMap fills the global variable 'GlobalScenes' with filepaths and contents.
 //
 // Every file for a scene has its own directory named with the scene name (its identifier throughout the game).
-// The files can be 'script.md', 'mapConfig.json' or '<objectName>.json' (not yet implemented):
-// - JSON files contain the map config
+// The files can be 'script.md', 'mapConfig.json' or '<objectName>.json':
 // - MD files contain the scene's script
+// - JSON files contain the map config or an object (see 'schema.go')
+//
+// Localized files like 'script.de.md' are loaded on top of the default ones for the language set with 'SetLanguage'.
 //
 // GO files are outside this structure and contain special functions which don't fit in the generic 'OnUpdate' handling.
-// For empty folders there will be an entry in the 'SceneMap' with default values.
+// For empty folders there will be an entry in the 'SceneMap' with default values. The 'SharedContentFolder' is skipped.
+//
+// Problems with the files are logged, the game can be played without the invalid entries.
 //
 // For some scenes special init functions are called (e.g. for the 'Demo' scene).
 func LoadFilesToSceneMap() {
 	GlobalScenes = make(map[string]*Scene)
 
-	contentFolders, err := ioutil.ReadDir(ContentDir)
+	if err := loadVerbDictionaryFromContent(ContentDir); err != nil {
+		log.Println(err)
+	}
+
+	sceneNames, err := readSceneNames(ContentDir)
 	if err != nil {
 		panic("Content directory '" + ContentDir + "' couldn't be read!")
 	}
-	for _, contentFolder := range contentFolders {
-
-		sceneName := contentFolder.Name()
+	for _, sceneName := range sceneNames {
 		buildSceneFromFolder(sceneName)
-		GlobalScenes[sceneName].objects = make(map[string]map[string]interface{})
+		err := GlobalScenes[sceneName].loadSceneFiles(ContentDir)
+		if _, isErrorList := err.(ScriptErrorList); isErrorList {
+			log.Println(err)
+		} else if err != nil {
+			panic("Content directory '" + ContentDir + sceneName + "' couldn't be read!")
+		}
+	}
+	addSpecialScenes()
+
+	globalContentModTimes = readContentModTimes(ContentDir)
+}
 
-		contentFiles, err := ioutil.ReadDir(ContentDir + contentFolder.Name())
-		if err != nil {
-			panic("Content directory '" + ContentDir + contentFolder.Name() + "' couldn't be read!")
+// loadSceneFiles loads the script, map config and objects from the scene's folder. Problems with the map config and
+// object files are returned as a 'ScriptErrorList' after everything else has been loaded.
+func (s *Scene) loadSceneFiles(contentDir string) error {
+	contentFiles, err := readSceneFolder(contentDir, s.Name)
+	if err != nil {
+		return err
+	}
+
+	s.script.parsed, s.script.parseErr = nil, nil
+	s.objects = make(map[string]*sceneObject)
+	var problems ScriptErrorList
+	addProblems := func(err error) {
+		if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+			problems = append(problems, errorList...)
 		}
-		for _, contentFile := range contentFiles {
-			if isTestFile(contentFile.Name()) {
-				continue
+	}
+	for _, contentFile := range contentFiles {
+		if contentFile.language != `` && contentFile.language != globalLanguage {
+			continue
+		}
+		if contentFile.name == `script` && contentFile.extension == `md` {
+			if contentFile.language != `` {
+				s.loadLocalizedScript(contentFile.path)
+			} else {
+				s.loadScript(contentFile.path)
 			}
-
-			contentFileFilter := regexp.MustCompile(`^(\w+)\.(md|json)$`)
-
-			fileMatchSlice := contentFileFilter.FindStringSubmatch(contentFile.Name())
-			if len(fileMatchSlice) == 3 {
-				filePath := ContentDir + sceneName + "/" + fileMatchSlice[0]
-				fileName := fileMatchSlice[1]
-				fileExtension := fileMatchSlice[2]
-
-				if fileName == `script` && fileExtension == `md` {
-					GlobalScenes[sceneName].script.filePath = filePath
-					GlobalScenes[sceneName].script.fileContent = fileio.LoadFileToString(filePath)
-				} else if fileName == `mapConfig` && fileExtension == `json` {
-					GlobalScenes[sceneName].mapConfigPath = filePath
-					GlobalScenes[sceneName].loadMapConfig(filePath)
-				} else {
-					GlobalScenes[sceneName].loadObject(filePath, fileName)
-				}
+		} else if contentFile.name == `mapConfig` && contentFile.extension == `json` {
+			if contentFile.language == `` {
+				s.mapConfigPath = contentFile.path
 			}
+			addProblems(s.loadMapConfig(contentFile.path))
+		} else {
+			addProblems(s.loadObject(contentFile.path, contentFile.name))
 		}
 	}
-	addSpecialScenes()
+	// Scenes without a valid map config can still be entered, they lead nowhere
+	if s.mapConfig == nil {
+		s.mapConfig = &MapConfig{}
+	}
+	s.objectNames = make(map[string]bool)
+	for objectName := range s.objects {
+		s.objectNames[objectName] = true
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
 }
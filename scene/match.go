@@ -0,0 +1,126 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file matches the player's input against the keywords of the active script section.
+package scene
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// normalizeCommand returns the canonical form of a command (see 'playerCommand.String') so that keywords written in
+// the script and the player's input can be compared.
+func normalizeCommand(command string) string {
+	return globalVerbDictionary.parseCommand(command).String()
+}
+
+// getMaxEditDistance returns how many typos are forgiven for a command of the given length.
+func getMaxEditDistance(command string) int {
+	if len(command) <= 6 {
+		return 1
+	}
+	return 2
+}
+
+// getEditDistance returns the Levenshtein distance between two strings.
+func getEditDistance(a, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	previousRow := make([]int, len(bRunes)+1)
+	currentRow := make([]int, len(bRunes)+1)
+	for j := range previousRow {
+		previousRow[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		currentRow[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			substitutionCost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				substitutionCost = 0
+			}
+			currentRow[j] = minInt(previousRow[j]+1, currentRow[j-1]+1, previousRow[j-1]+substitutionCost)
+		}
+		previousRow, currentRow = currentRow, previousRow
+	}
+	return previousRow[len(bRunes)]
+}
+
+func minInt(first int, others ...int) int {
+	min := first
+	for _, other := range others {
+		if other < min {
+			min = other
+		}
+	}
+	return min
+}
+
+// hasMetAlternative checks whether any of the alternatives would answer the player right now.
+func hasMetAlternative(alternatives []keywordAlternative) bool {
+	for _, alternative := range alternatives {
+		if alternative.condition.isMet() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKeyword looks up the alternatives for the player's command.
+//
+// If there is no exact match, keywords within a small edit distance are accepted. If several different keywords are
+// equally close they are returned as candidates (as written in the script) so the player can be asked which one was
+// meant.
+func (s *Scene) matchKeyword(command playerCommand) (alternatives []keywordAlternative, candidates []string) {
+	normalizedCommand := command.String()
+	if normalizedCommand == `` {
+		return nil, nil
+	}
+	if alternatives, isFound := s.script.keywordResponseMap[normalizedCommand]; isFound {
+		return alternatives, nil
+	}
+
+	// Keywords further away than the typos forgiven for the command never match
+	bestDistance := getMaxEditDistance(normalizedCommand)
+	// closestKeywords maps the keyword as written in the script to its alternatives, synonyms end up in one entry
+	closestKeywords := make(map[string][]keywordAlternative)
+	for synonym, synonymAlternatives := range s.script.keywordResponseMap {
+		if !hasMetAlternative(synonymAlternatives) {
+			continue
+		}
+		distance := getEditDistance(normalizedCommand, synonym)
+		if distance > bestDistance {
+			continue
+		}
+		if distance < bestDistance {
+			bestDistance = distance
+			closestKeywords = make(map[string][]keywordAlternative)
+		}
+		closestKeywords[synonymAlternatives[0].keyword] = synonymAlternatives
+	}
+
+	if len(closestKeywords) == 1 {
+		for _, closestAlternatives := range closestKeywords {
+			return closestAlternatives, nil
+		}
+	}
+	for keyword := range closestKeywords {
+		candidates = append(candidates, keyword)
+	}
+	sort.Strings(candidates)
+	return nil, candidates
+}
+
+// getClarifyingQuestion asks the player which of the candidate keywords was meant.
+func getClarifyingQuestion(candidates []string) string {
+	quotedCandidates := make([]string, len(candidates))
+	for idx, candidate := range candidates {
+		quotedCandidates[idx] = `'` + candidate + `'`
+	}
+	if len(quotedCandidates) == 1 {
+		return fmt.Sprintf(translate(`Did you mean %s?`), quotedCandidates[0])
+	}
+	return fmt.Sprintf(translate(`Did you mean %s?`), fmt.Sprintf(translate(`%s or %s`),
+		strings.Join(quotedCandidates[:len(quotedCandidates)-1], `, `), quotedCandidates[len(quotedCandidates)-1]))
+}
@@ -50,6 +50,8 @@ type Narrator struct {
 
 	// currentTextString contains a string stripped from any markup symbols.
 	currentTextString string
+	// currentTextSource contains the string as it has been set including the markup, see 'SaveGame'
+	currentTextSource string
 
 	textBox *TextBox
 }
@@ -120,6 +122,10 @@ func getMarkdownCommandSliceFromString(str string) ([]markdownCommand, string) {
 		for _, HTMLstyleSlice := range currHTMLStyleSlicesSlice {
 			currMarkdownCommand.attributeValueMap[HTMLstyleSlice[1]] = HTMLstyleSlice[2]
 		}
+		// e.g. <span word="compass"> marks a word to collect, see 'words.go'
+		if wordMatch := wordAttributeRegexp.FindStringSubmatch(styleMatchSlice[i][0]); wordMatch != nil {
+			currMarkdownCommand.attributeValueMap[`word`] = wordMatch[1]
+		}
 
 		currMarkdownCommand.idxStart = styleIndexStartSlice[i][0] - cumulativeOffset
 		cumulativeOffset += styleIndexStartSlice[i][1] - styleIndexStartSlice[i][0]
@@ -154,7 +160,7 @@ func (n *Narrator) applyMarkdownCommand(markdownCommandSlice *[]markdownCommand,
 				if err != nil {
 					panic(err)
 				}
-				n.atlas = text.NewAtlas(face, text.ASCII)
+				n.atlas = text.NewAtlas(face, text.ASCII, translationRunes)
 			case `text-speed`:
 				strippedValue := strings.Replace(value, `cpm`, ``, 1)
 				textSpeed, err := strconv.Atoi(strippedValue)
@@ -162,6 +168,10 @@ func (n *Narrator) applyMarkdownCommand(markdownCommandSlice *[]markdownCommand,
 					panic(err)
 				}
 				n.textSpeed = textSpeed
+			case `word`:
+				if _, hasColor := (*markdownCommandSlice)[0].attributeValueMap[`color`]; !hasColor {
+					n.color = collectableWordColor
+				}
 			}
 		}
 	} else if idx == (*markdownCommandSlice)[0].idxEnd {
@@ -232,7 +242,7 @@ func (n *Narrator) setTextRangeFontFace(face font.Face, indexStart, indexEnd int
 			break
 		}
 		textObj = &NarratorText{
-			Text:      text.New(textObj.Orig, text.NewAtlas(face, text.ASCII)),
+			Text:      text.New(textObj.Orig, text.NewAtlas(face, text.ASCII, translationRunes)),
 			textSpeed: n.textSpeed}
 		// The newly created *text.Text doesn't contain any glyphs to draw yet
 		currLetter := string(n.currentTextString[idx])
@@ -282,7 +292,13 @@ func (n *Narrator) graduallyRevealText(scn *Scene) {
 // Online LF "\n" is used to mark a new line.
 func (n *Narrator) setTextLetterByLetter(str string, scn *Scene) {
 
+	n.currentTextSource = str
 	n.convertMarkdownStringToTextObjectsInBox(str, scn)
+	globalPlayer.collectWords(str)
+	if globalTerminal != nil {
+		globalTerminal.printNarratorText(n.currentTextString)
+		return
+	}
 	go n.graduallyRevealText(scn)
 }
 
@@ -0,0 +1,201 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file lets the player interact with the objects of a scene as their JSON files describe it, e.g.
+//
+//	{
+//	    "id": "oldCupboard1",
+//	    "lock_state": "locked",
+//	    "actions": {
+//	        "look": "This appears to be an old cupboard.",
+//	        "open": {
+//	            "lock_state=locked": "The door won't budge, it is locked tight.",
+//	            "lock_state=unlocked": "You open the door of the cupboard."
+//	        }
+//	    },
+//	    "conditions": {
+//	        "keyCupboard1": {
+//	            "action": "use",
+//	            "target": "oldCupboard1",
+//	            "attribute_change": {"target": "oldCupboard1", "attribute": "lock_state", "value": "unlocked"},
+//	            "message": "The key fits and the door now appears to be unlocked"
+//	        }
+//	    }
+//	}
+package scene
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// getObjectID returns the object's 'id' which conditions refer to, objects without one are referred to by their name.
+func getObjectID(objectName string, object *sceneObject) string {
+	if object.ID != `` {
+		return object.ID
+	}
+	return objectName
+}
+
+// findReachableObject returns the object the player refers to if it is in the scene or carried by the player.
+func (s *Scene) findReachableObject(commandObject string) (string, *sceneObject) {
+	if objectName := findObject(s.objects, commandObject); objectName != `` {
+		return objectName, s.objects[objectName]
+	}
+	if objectName := findObject(globalPlayer.items, commandObject); objectName != `` {
+		return objectName, globalPlayer.items[objectName]
+	}
+	return ``, nil
+}
+
+// findObjectByID returns the object with the id, looking in the current scene, the player's items and then the other
+// scenes.
+func findObjectByID(id string) *sceneObject {
+	var sceneNames []string
+	for sceneName := range GlobalScenes {
+		if sceneName != GlobalCurrentScene {
+			sceneNames = append(sceneNames, sceneName)
+		}
+	}
+	sort.Strings(sceneNames)
+
+	objectMaps := []map[string]*sceneObject{globalPlayer.items}
+	if s := GlobalScenes[GlobalCurrentScene]; s != nil {
+		objectMaps = append([]map[string]*sceneObject{s.objects}, objectMaps...)
+	}
+	for _, sceneName := range sceneNames {
+		objectMaps = append(objectMaps, GlobalScenes[sceneName].objects)
+	}
+	for _, objects := range objectMaps {
+		for objectName, object := range objects {
+			if getObjectID(objectName, object) == id {
+				return object
+			}
+		}
+	}
+	return nil
+}
+
+// hasAttribute evaluates an attribute condition like 'lock_state=locked' against the object.
+func (o *sceneObject) hasAttribute(attributeCondition string) bool {
+	attribute, value := splitAttributeCondition(attributeCondition)
+	attributeValue, isFound := o.Attributes[attribute]
+	return isFound && attributeValue == value
+}
+
+// splitAttributeCondition splits 'lock_state=locked' into the attribute and its value.
+func splitAttributeCondition(attributeCondition string) (attribute, value string) {
+	parts := strings.SplitN(attributeCondition, `=`, 2)
+	if len(parts) < 2 {
+		return strings.TrimSpace(parts[0]), ``
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+// getActionText returns the text of the object's action for the verb. An action is either a text or maps attribute
+// conditions to texts of which the first one met (in alphabetical order of the conditions) is returned.
+func (o *sceneObject) getActionText(verb string) (string, bool) {
+	action := o.Actions[verb]
+	if action == nil {
+		return ``, false
+	}
+	if action.ConditionalTexts == nil {
+		return action.Text, true
+	}
+
+	var attributeConditions []string
+	for attributeCondition := range action.ConditionalTexts {
+		attributeConditions = append(attributeConditions, attributeCondition)
+	}
+	sort.Strings(attributeConditions)
+	for _, attributeCondition := range attributeConditions {
+		if o.hasAttribute(attributeCondition) {
+			return action.ConditionalTexts[attributeCondition], true
+		}
+	}
+	return ``, false
+}
+
+// applyCondition executes the condition which the target object has for the item (e.g. 'keyCupboard1'), i.e. its
+// attribute change is applied and kept. It returns the condition's message or false if the target has no such
+// condition for the verb.
+func applyCondition(verb string, itemID string, target *sceneObject, targetID string) (string, bool) {
+	condition := target.Conditions[itemID]
+	if condition == nil || condition.Action != verb {
+		return ``, false
+	}
+	if condition.Target != `` && condition.Target != targetID {
+		return ``, false
+	}
+
+	if change := condition.AttributeChange; change != nil {
+		changedObject := target
+		if change.Target != `` && change.Target != targetID {
+			changedObject = findObjectByID(change.Target)
+		}
+		if changedObject != nil {
+			if changedObject.Attributes == nil {
+				changedObject.Attributes = make(map[string]string)
+			}
+			changedObject.Attributes[change.Attribute] = change.Value
+		}
+	}
+	return condition.Message, true
+}
+
+// handleObjectActions executes the built-in verbs 'look', 'open' and 'use' on the objects in the scene or carried by
+// the player. It returns false if the command doesn't refer to such an object or the object has nothing to say about
+// it so script keywords close to the input get their chance.
+func (s *Scene) handleObjectActions(command playerCommand) bool {
+	if command.object == `` {
+		switch command.verb {
+		case `open`:
+			globalNarrator.setTextLetterByLetter(translate("What do you want to open?"), s)
+			return true
+		case `use`:
+			globalNarrator.setTextLetterByLetter(translate("What do you want to use?"), s)
+			return true
+		}
+		return false
+	}
+
+	objectName, object := s.findReachableObject(command.object)
+	if objectName == `` {
+		return false
+	}
+
+	if command.verb == `use` && command.indirectObject != `` {
+		targetName, target := s.findReachableObject(command.indirectObject)
+		if targetName == `` {
+			return false
+		}
+		message, isApplied := applyCondition(command.verb, getObjectID(objectName, object), target,
+			getObjectID(targetName, target))
+		if !isApplied {
+			message = fmt.Sprintf(translate("Nothing happens when you use the %s on the %s."),
+				getObjectName(objectName, object), getObjectName(targetName, target))
+		}
+		globalNarrator.setTextLetterByLetter(message, s)
+		return true
+	}
+
+	if text, hasAction := object.getActionText(command.verb); hasAction {
+		globalNarrator.setTextLetterByLetter(text, s)
+		return true
+	}
+	switch command.verb {
+	case `look`:
+		text := object.Description
+		if text == `` {
+			text = fmt.Sprintf(translate("You see nothing special about the %s."), getObjectName(objectName, object))
+		}
+		globalNarrator.setTextLetterByLetter(text, s)
+	case `use`:
+		globalNarrator.setTextLetterByLetter(fmt.Sprintf(translate("What do you want to use the %s on?"),
+			getObjectName(objectName, object)), s)
+	default:
+		return false
+	}
+	return true
+}
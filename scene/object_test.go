@@ -0,0 +1,63 @@
+package scene
+
+import (
+	"testing"
+)
+
+func TestObjectActions(t *testing.T) {
+	previousScenes, previousCurrentScene := GlobalScenes, GlobalCurrentScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene = previousScenes, previousCurrentScene
+		globalPlayer.items = nil
+	}()
+	globalPlayer.items = nil
+
+	lighthouse := getSceneObjectWithDefaults()
+	lighthouse.Name = `Lighthouse`
+	lighthouse.objects = map[string]*sceneObject{
+		`lamp`: {
+			ID:         `lamp1`,
+			Attributes: map[string]string{`light_state`: `off`},
+			Actions: map[string]*objectAction{
+				`look`: {ConditionalTexts: map[string]string{
+					`light_state=off`: `The lamp is dark.`,
+					`light_state=on`:  `The lamp shines over the sea.`,
+				}},
+			},
+		},
+		`lever`: {
+			ID: `lever1`,
+			Conditions: map[string]*objectCondition{
+				`handCrank1`: {
+					Action:          `use`,
+					Target:          `lever1`,
+					AttributeChange: &attributeChange{Target: `lamp1`, Attribute: `light_state`, Value: `on`},
+					Message:         `The crank turns the lever.`,
+				},
+			},
+		},
+	}
+	globalPlayer.addItem(`handCrank`, &sceneObject{ID: `handCrank1`, Name: `hand crank`})
+	GlobalScenes = map[string]*Scene{`Lighthouse`: lighthouse}
+	GlobalCurrentScene = `Lighthouse`
+
+	tests := []struct {
+		input        string
+		expectedText string
+	}{
+		{`look lamp`, `The lamp is dark.`},
+		{`look lever`, `You see nothing special about the lever.`},
+		{`use`, `What do you want to use?`},
+		{`use hand crank`, `What do you want to use the hand crank on?`},
+		{`use lamp with hand crank`, `Nothing happens when you use the lamp on the hand crank.`},
+		{`use hand crank on lever`, `The crank turns the lever.`},
+		{`look lamp`, `The lamp shines over the sea.`},
+	}
+	for _, test := range tests {
+		lighthouse.handlePlayerCommand(test.input)
+		if globalNarrator.currentTextString != test.expectedText {
+			t.Errorf("Expected '%s' to be answered with '%s' but got '%s'", test.input, test.expectedText,
+				globalNarrator.currentTextString)
+		}
+	}
+}
@@ -3,132 +3,36 @@
 package scene
 
 import (
-	"regexp"
+	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/3ter/iMagine/fileio"
 	"github.com/faiface/beep/speaker"
 )
 
-// getMatchedAmbienceCmd removes the command marker from a string and returns it.
-// If no match was found it returns an empty string which has length 0.
-func getMatchedAmbienceCmd(line string) string {
-	ambienceCmdMarkerRegexp := regexp.MustCompile("^`\\[(.+)\\]`$")
-	submatchSlice := ambienceCmdMarkerRegexp.FindStringSubmatch(line)
-	if len(submatchSlice) > 0 {
-		return submatchSlice[1]
-	}
-	return ""
-}
-
-// getCombinedAmbienceTextResponse collects ambience commands in a slice until the first text line gets
-// the accumulated commands added to the narratorResponse struct.
-//
-// This could also be done with a closure but the additional parameter seemed easier.
-func getCombinedAmbienceTextResponse(line string, ambienceCmdSlice *[]string) narratorResponse {
-	ambienceCmd := getMatchedAmbienceCmd(line)
-	if len(ambienceCmd) > 0 {
-		*ambienceCmdSlice = append(*ambienceCmdSlice, ambienceCmd)
-	}
-
-	// Empty the previous ambience slice to fill the text (non-command) line with it
-	cmdRegexp := regexp.MustCompile("^`")
-	if !cmdRegexp.MatchString(line) {
-		response := narratorResponse{
-			narratorTextLine: line,
-		}
-		response.ambienceCmdSlice = *ambienceCmdSlice
-		*ambienceCmdSlice = nil
-		return response
-	}
-	return narratorResponse{}
-}
-
-// getActiveScriptSlice uses the already loaded script data and returns the current script based on s.progress.
-func (s *Scene) getActiveScriptSlice() []string {
-
-	if len(s.script.fileContent) <= 0 {
-		panic("Script file hasn't been loaded into string.")
-	}
-
-	var activeScript string
-
-	// Find currently active script part and remove progress line
-	hashRegexp := regexp.MustCompile(`(?m:^# )`)
-	scriptParts := hashRegexp.Split(s.script.fileContent, -1)
-	if len(scriptParts) <= 0 {
-		panic("Script doesn't contain at least one part marked by '#'.")
-	}
-
-	progressRegexp := regexp.MustCompile(`^` + s.progress + `\r?\n`)
-	for _, scriptPart := range scriptParts {
-		if progressRegexp.MatchString(scriptPart) {
-			activeScript = progressRegexp.ReplaceAllString(scriptPart, ``)
-			break
-		}
-	}
-	if len(activeScript) <= 0 {
-		panic("Active script empty after removal of progress marker.")
-	}
-
-	// Separate directives (ambience / text / keywords) by a blank line
-	blankLineRegexp := regexp.MustCompile(`\r?\n\r?\n`)
-	activeScriptSlice := blankLineRegexp.Split(activeScript, -1)
-	activeScriptSlice = activeScriptSlice[:len(activeScriptSlice)-1] // to remove last element (empty string)
-
-	return activeScriptSlice
-}
-
-// getKeywordResponseMap gobbles up the rest of the lines from lineNumber onwards when it encounters the first
-// player command marker (parentheses).
-func getKeywordResponseMap(line string, lineNumber int, activeScriptSlice []string) map[string][]narratorResponse {
-
-	playerCmdMarkerRegexp := regexp.MustCompile("^`\\((.+)\\)(?: > (.+))?`$")
-	submatchSlice := playerCmdMarkerRegexp.FindStringSubmatch(line)
-	if len(submatchSlice) == 0 {
-		return map[string][]narratorResponse{}
-	}
-
-	var keywordResponseMap = make(map[string][]narratorResponse)
-	var ambienceCmdSlice []string
-
-	var currentKeyword string
-	for _, scriptLine := range activeScriptSlice[lineNumber:] {
-		submatchSlice := playerCmdMarkerRegexp.FindStringSubmatch(scriptLine)
-		if len(submatchSlice) > 0 {
-			currentKeyword = submatchSlice[1]
-		}
-		if len(submatchSlice) > 2 && submatchSlice[2] != "" {
-			// Two (sub)matches mean no more messages to come but a jump to a new progress state
-			keywordResponseMap[currentKeyword] =
-				append(keywordResponseMap[currentKeyword], narratorResponse{
-					progressUpdate: submatchSlice[2],
-				})
-			continue
-		}
-
-		response := getCombinedAmbienceTextResponse(scriptLine, &ambienceCmdSlice)
-		if response.narratorTextLine != "" {
-			keywordResponseMap[currentKeyword] =
-				append(keywordResponseMap[currentKeyword], response)
-		}
-	}
-
-	return keywordResponseMap
-}
-
-func executeAmbienceCommands(ambienceCmdSlice []string) {
+// executeAmbienceCommands plays the sounds and changes the world state. While playing in a terminal every directive is
+// written as an annotation instead and nothing is played.
+func executeAmbienceCommands(ambienceCmdSlice []*ambienceDirective) {
 	for _, ambienceCmd := range ambienceCmdSlice {
-		ambientTypeRegexp := regexp.MustCompile(`^(\w+):\s?`)
-		ambientType := ambientTypeRegexp.FindStringSubmatch(ambienceCmd)[1]
-
-		switch ambientType {
+		if globalTerminal != nil {
+			globalTerminal.printAmbience(ambienceCmd)
+		}
+		switch ambienceCmd.kind {
 		case `Audio`:
-			// Don't allow whitespace chars in filenames
-			audioFileRegexp := regexp.MustCompile(`^Audio:\s?(\S+)`)
-			audioFilename := audioFileRegexp.FindStringSubmatch(ambienceCmd)[1]
-			var streamer = fileio.GetStreamer("../assets/" + audioFilename)
+			if globalTerminal != nil {
+				continue
+			}
+			var streamer = fileio.GetStreamer(AssetsDir + ambienceCmd.argument)
 			speaker.Play(streamer)
+		case `Set`:
+			globalWorld.executeSet(ambienceCmd.argument)
+		case `Add`:
+			globalWorld.executeAdd(ambienceCmd.argument)
+		case giveDirective:
+			giveItem(ambienceCmd.argument)
 		}
 	}
 }
@@ -146,66 +50,234 @@ func translateDirectionToSceneName(direction string) string {
 	return sceneName
 }
 
-func (s *Scene) handleActions(playerWords []string) {
+// catchAllKeyword is written as `(*)` and answers everything the section doesn't understand otherwise.
+const catchAllKeyword = `*`
 
-	if len(playerWords) < 2 {
-		globalNarrator.setTextLetterByLetter("Specify your command in the format: '[verb] [object]'", s)
-		return
-	}
-	verb := strings.ToLower(playerWords[0])
-	object := strings.ToLower(playerWords[1])
-	sceneName := translateDirectionToSceneName(object)
-	switch verb {
+// globalFallbackLines answer unknown commands if neither the section nor the scene has something to say. They are
+// translated, see 'translations'.
+var globalFallbackLines = []string{
+	"I don't understand '{input}'.",
+	"You think about how to '{input}', but nothing comes to mind.",
+	"'{input}'? Nothing happens.",
+}
+
+// handleActions executes the built-in verbs which work in every scene and returns whether the command has been handled.
+func (s *Scene) handleActions(command playerCommand) bool {
+
+	switch command.verb {
+	case `take`, `drop`, `inventory`:
+		return s.handleItemActions(command)
+	case `open`, `use`:
+		return s.handleObjectActions(command)
 	case `go`:
+		if command.object == `` {
+			globalNarrator.setTextLetterByLetter(translate("Where do you want to go? (Enter a direction: e.g. North)"), s)
+			return true
+		}
+		sceneName := translateDirectionToSceneName(command.object)
 		if GlobalScenes[sceneName] == nil || sceneName == `Void` {
-			globalNarrator.setTextLetterByLetter("You can't go to '"+object+"'! (Enter a direction: e.g. North)", s)
-			return
+			globalNarrator.setTextLetterByLetter(
+				fmt.Sprintf(translate("You can't go to '%s'! (Enter a direction: e.g. North)"), command.object), s)
+			return true
 		}
 		// To allow parsing of the newly selected current script file (see 'scene.OnUpdate')
-		s.script.keywordResponseMap = nil
+		s.resetSectionKeywords()
 		GlobalCurrentScene = sceneName
 	case `look`:
-		if sceneName == `around` {
+		if command.object == `` || command.object == `around` {
 			var lookMessages []string
 			for direction, sceneInDirection := range GlobalScenes[GlobalCurrentScene].mapConfig.Directions {
 				lookMessages = append(lookMessages,
 					direction+": "+GlobalScenes[sceneInDirection].mapConfig.Look)
 			}
+			// Map iteration order is random, sorted the directions read the same every time
+			sort.Strings(lookMessages)
 			globalNarrator.setTextLetterByLetter(strings.Join(lookMessages, "\n"), s)
-		} else if GlobalScenes[sceneName] == nil {
-			globalNarrator.setTextLetterByLetter("You can't look to '"+object+"'! (Enter a direction: e.g. North)", s)
-			return
-		} else {
-			globalNarrator.setTextLetterByLetter(GlobalScenes[sceneName].mapConfig.Look, s)
+			return true
+		}
+		if s.handleObjectActions(command) {
+			return true
+		}
+		sceneName := translateDirectionToSceneName(command.object)
+		if GlobalScenes[sceneName] == nil {
+			globalNarrator.setTextLetterByLetter(
+				fmt.Sprintf(translate("You can't look to '%s'! (Enter a direction: e.g. North)"), command.object), s)
+			return true
 		}
+		globalNarrator.setTextLetterByLetter(GlobalScenes[sceneName].mapConfig.Look, s)
 	}
+	return true
 }
 
+// handlePlayerCommand answers the player's input.
+//
+// Script keywords are tried first so scripts can override the built-in verbs, then the built-in verbs and at last
+// keywords which are only close to the input.
 func (s *Scene) handlePlayerCommand(playerInput string) {
 
-	playerWords := strings.Split(playerInput, ` `)
+	command := globalVerbDictionary.parseCommand(playerInput)
+	if command.verb == `` {
+		return
+	}
+	globalPlayer.lastInput = playerInput
+
+	if alternatives := s.script.keywordResponseMap[command.String()]; hasMetAlternative(alternatives) {
+		s.deliverKeywordResponse(alternatives)
+		return
+	}
+
+	if builtinVerbs[command.verb] && s.handleActions(command) {
+		return
+	}
 
-	if len(playerWords[0]) > 0 && (playerWords[0] == `go` || playerWords[0] == `look`) {
-		s.handleActions(playerWords)
+	alternatives, candidates := s.matchKeyword(command)
+	if len(candidates) > 0 {
+		globalNarrator.setTextLetterByLetter(getClarifyingQuestion(candidates), s)
+		return
+	}
+	if s.deliverKeywordResponse(alternatives) {
 		return
 	}
 
-	// Check for progress change
-	for keyword, responseSlice := range s.script.keywordResponseMap {
-		if strings.EqualFold(playerInput, keyword) {
-			// If there's a progressUpdate then there's only one response in the slice
-			if responseSlice[0].progressUpdate != "" {
-				s.progress = responseSlice[0].progressUpdate
-				// Empty keywordResponseMap to prepare for jump to new script section.
-				s.script.keywordResponseMap = map[string][]narratorResponse{}
-				s.parseScriptFile()
-				s.executeScriptFromQueue()
-			} else {
-				executeAmbienceCommands(s.script.keywordResponseMap[keyword][0].ambienceCmdSlice)
-				globalNarrator.setTextLetterByLetter(s.script.keywordResponseMap[keyword][0].narratorTextLine, s)
+	s.handleUnknownCommand()
+}
+
+// deliverKeywordResponse queues the responses of the first alternative whose condition is met and returns whether
+// there was one.
+func (s *Scene) deliverKeywordResponse(alternatives []keywordAlternative) bool {
+	for _, alternative := range alternatives {
+		if alternative.condition.isMet() {
+			// All paragraphs of the answer are delivered with Enter-to-continue like the section's narrator lines
+			s.script.responseQueue = append(s.script.responseQueue, s.chooseVariant(alternative)...)
+			s.deliverNextResponse()
+			return true
+		}
+	}
+	return false
+}
+
+// handleUnknownCommand answers input which matches neither a keyword nor a built-in verb so it doesn't silently
+// disappear. The first of these that exists answers:
+// - the section's `(*)` keyword
+// - one of the scene's fallback lines from its map config
+// - one of the global fallback lines
+func (s *Scene) handleUnknownCommand() {
+	if s.deliverKeywordResponse(s.script.catchAllAlternatives) {
+		return
+	}
+
+	fallbackLines := globalFallbackLines
+	if s.mapConfig != nil && len(s.mapConfig.Fallback) > 0 {
+		fallbackLines = s.mapConfig.Fallback
+	}
+	fallbackLine := fallbackLines[globalRandom.Intn(len(fallbackLines))]
+	globalNarrator.setTextLetterByLetter(interpolateVariables(translate(fallbackLine)), s)
+}
+
+// chooseVariant returns the responses of the variant which is next according to the alternative's variant mode.
+func (s *Scene) chooseVariant(alternative keywordAlternative) []narratorResponse {
+	if len(alternative.variants) == 1 {
+		return alternative.variants[0]
+	}
+
+	if s.script.variantCounters == nil {
+		s.script.variantCounters = make(map[string]int)
+	}
+	deliveredCount := s.script.variantCounters[alternative.variantKey]
+	s.script.variantCounters[alternative.variantKey]++
+
+	switch alternative.variantMode {
+	case variantModeRandom:
+		return alternative.variants[globalRandom.Intn(len(alternative.variants))]
+	case variantModeOnce:
+		if deliveredCount >= len(alternative.variants) {
+			deliveredCount = len(alternative.variants) - 1
+		}
+		return alternative.variants[deliveredCount]
+	default:
+		return alternative.variants[deliveredCount%len(alternative.variants)]
+	}
+}
+
+// deliverNextResponse executes the next response from the 'responseQueue' and returns whether the queue had anything
+// to show.
+//
+// A response with a progressUpdate jumps to the new script section and continues with its narrator lines.
+func (s *Scene) deliverNextResponse() bool {
+
+	// Enter moves on before an `[AutoAdvance: ...]` is due
+	s.script.autoAdvanceAt = time.Time{}
+
+	for len(s.script.responseQueue) > 0 {
+		response := s.script.responseQueue[0]
+		s.script.responseQueue = s.script.responseQueue[1:]
+
+		if response.progressUpdate != "" {
+			if sceneName, section := splitProgressTarget(response.progressUpdate); sceneName != `` && sceneName != s.Name {
+				s.jumpToScene(sceneName, section)
+				return true
+			}
+			_, s.progress = splitProgressTarget(response.progressUpdate)
+			// Empty keywordResponseMap to prepare for jump to new script section.
+			s.script.keywordResponseMap = map[string][]keywordAlternative{}
+			if err := s.loadActiveSection(); err != nil {
+				s.reportScriptError(err)
+				return true
 			}
+			autosave()
+			continue
+		}
+		if !response.condition.isMet() {
+			continue
+		}
+		if wait := getDirectiveDuration(response.ambienceCmdSlice, waitDirective); wait > 0 && !response.isWaitOver {
+			response.isWaitOver = true
+			s.script.responseQueue = append([]narratorResponse{response}, s.script.responseQueue...)
+			s.script.waitUntil = globalClock.Now().Add(wait)
+			return true
 		}
+
+		executeAmbienceCommands(response.ambienceCmdSlice)
+
+		// Ambience directives at the end of a section don't come with text to wait for
+		if response.narratorTextLine == "" {
+			continue
+		}
+		globalNarrator.setTextLetterByLetter(interpolateVariables(response.narratorTextLine), s)
+		if autoAdvance := getDirectiveDuration(response.ambienceCmdSlice, autoAdvanceDirective); autoAdvance > 0 {
+			s.script.autoAdvanceAt = globalClock.Now().Add(autoAdvance)
+		}
+		return true
 	}
+	return false
+}
+
+// splitProgressTarget splits a jump target like 'Lighthouse#entrance' into scene and section name.
+//
+// The scene name is empty for jumps inside of the current scene like 'get_compass'.
+func splitProgressTarget(target string) (sceneName, section string) {
+	if idx := strings.Index(target, `#`); idx >= 0 {
+		return target[:idx], target[idx+1:]
+	}
+	return ``, target
+}
+
+// jumpToScene switches to another scene and starts its script at the given section.
+func (s *Scene) jumpToScene(sceneName, section string) {
+	destination := GlobalScenes[sceneName]
+	if destination == nil || isSpecialScene(sceneName) {
+		s.reportScriptError(fmt.Errorf("%s: there is no scene '%s' to jump to", s.script.filePath, sceneName))
+		return
+	}
+
+	// The destination loads its section when it becomes active (see 'scene.OnUpdate') like after 'go'
+	destination.progress = section
+	destination.script.responseQueue = nil
+	destination.resetSectionKeywords()
+
+	s.script.responseQueue = nil
+	s.resetSectionKeywords()
+	GlobalCurrentScene = sceneName
 }
 
 // executeScriptFromQueue modfies the scene according to scene script and player input.
@@ -215,40 +287,99 @@ func (s *Scene) handlePlayerCommand(playerInput string) {
 // The check for progress change provides a way to jump from section within a scene script.
 func (s *Scene) executeScriptFromQueue() {
 
-	if len(s.script.responseQueue) > 0 {
-		executeAmbienceCommands(s.script.responseQueue[0].ambienceCmdSlice)
-	}
-
-	// Set narrator text
-	if len(s.script.responseQueue) > 0 {
-		globalNarrator.setTextLetterByLetter(s.script.responseQueue[0].narratorTextLine, s)
-		s.script.responseQueue = s.script.responseQueue[1:]
+	if s.deliverNextResponse() {
 		return
 	}
 
 	playerInput := globalPlayer.currentTextString
 	globalPlayer.setText("")
 
-	s.handlePlayerCommand(playerInput)
+	s.handlePlayerInput(playerInput)
 }
 
-func (s *Scene) parseScriptFile() {
-
-	activeScriptSlice := s.getActiveScriptSlice()
+// getNarratorResponse converts a parsed narrator line into the response delivered by the scene.
+func getNarratorResponse(line *narratorLine) narratorResponse {
+	return narratorResponse{
+		narratorTextLine: line.text,
+		ambienceCmdSlice: line.ambience,
+		condition:        line.condition,
+	}
+}
 
-	var ambienceCmdSlice []string
-	for lineNumber, scriptLine := range activeScriptSlice {
+// loadActiveSection fills the response queue and the keyword map from the script section matching s.progress.
+func (s *Scene) loadActiveSection() error {
 
-		response := getCombinedAmbienceTextResponse(scriptLine, &ambienceCmdSlice)
-		if response.narratorTextLine != "" {
-			s.script.responseQueue = append(s.script.responseQueue, response)
+	if s.script.parsed == nil {
+		if s.script.parseErr != nil {
+			return s.script.parseErr
 		}
+		return fmt.Errorf("scene '%s' has no script", s.Name)
+	}
+
+	section, isFound := s.script.parsed.sectionMap[s.progress]
+	if !isFound {
+		return fmt.Errorf("%s: there is no section '# %s'", s.script.filePath, s.progress)
+	}
 
-		// break loop if the first player keyword is found and gobble up the rest of the lines
-		keywordResponseMap := getKeywordResponseMap(scriptLine, lineNumber, activeScriptSlice)
-		if len(keywordResponseMap) > 0 {
-			s.script.keywordResponseMap = keywordResponseMap
-			break
+	for _, line := range section.lines {
+		s.script.responseQueue = append(s.script.responseQueue, getNarratorResponse(line))
+	}
+	s.loadSectionKeywords(section)
+
+	return nil
+}
+
+// loadSectionKeywords fills the keyword map and the idle responses from the section without queuing its lines.
+func (s *Scene) loadSectionKeywords(section *scriptSection) {
+	s.script.idleResponses = nil
+	for _, line := range section.idleLines {
+		s.script.idleResponses = append(s.script.idleResponses, getNarratorResponse(line))
+	}
+	s.noteActivity()
+
+	s.script.keywordResponseMap = make(map[string][]keywordAlternative)
+	s.script.catchAllAlternatives = nil
+	for _, keyword := range section.keywords {
+		var variants [][]narratorResponse
+		for _, variantLines := range keyword.variants {
+			var responseSlice []narratorResponse
+			for _, line := range variantLines {
+				responseSlice = append(responseSlice, getNarratorResponse(line))
+			}
+			// The jump happens after the keyword's own narrator lines have been delivered
+			if keyword.progressTarget != "" {
+				responseSlice = append(responseSlice, narratorResponse{
+					progressUpdate: keyword.progressTarget,
+				})
+			}
+			variants = append(variants, responseSlice)
+		}
+		alternative := keywordAlternative{
+			keyword:     keyword.keyword,
+			condition:   keyword.condition,
+			variants:    variants,
+			variantMode: keyword.variantMode,
+			variantKey:  keyword.pos.String(),
+		}
+		if keyword.keyword == catchAllKeyword {
+			s.script.catchAllAlternatives = append(s.script.catchAllAlternatives, alternative)
+			continue
+		}
+		isAdded := make(map[string]bool)
+		for _, synonym := range keyword.synonyms {
+			normalizedSynonym := normalizeCommand(synonym)
+			if isAdded[normalizedSynonym] {
+				continue
+			}
+			isAdded[normalizedSynonym] = true
+			s.script.keywordResponseMap[normalizedSynonym] =
+				append(s.script.keywordResponseMap[normalizedSynonym], alternative)
 		}
 	}
 }
+
+// reportScriptError shows script errors to the writer in the narrator box instead of crashing the game.
+func (s *Scene) reportScriptError(err error) {
+	log.Println(err)
+	globalNarrator.setTextLetterByLetter(err.Error(), s)
+}
@@ -22,6 +22,11 @@ import (
 // expected to be created in this package.
 type Player struct {
 	wordInventory []string
+	// items contains the objects the player carries by name (e.g. 'cupboardKey'), they are put back into a scene when
+	// dropped
+	items map[string]*sceneObject
+	// lastInput is the last command the player entered, scripts can refer to it with '{input}'
+	lastInput string
 
 	atlas    *text.Atlas
 	fontFace font.Face
@@ -37,9 +42,14 @@ type Player struct {
 	textBox *TextBox
 }
 
+func (p *Player) hasItem(itemName string) bool {
+	_, isCarried := p.items[itemName]
+	return isCarried
+}
+
 func (p *Player) setTextFontFace(face font.Face) {
 	textObject := p.currentTextObjects[0]
-	textObject = text.New(textObject.Orig, text.NewAtlas(face, text.ASCII))
+	textObject = text.New(textObject.Orig, text.NewAtlas(face, text.ASCII, translationRunes))
 	// The newly created *text.Text doesn't contain any glyphs to draw yet
 	textObject.WriteString(p.currentTextString)
 }
@@ -94,17 +104,17 @@ func (p *Player) setText(str string) {
 	wrappedString := p.getWrappedString(str)
 
 	p.currentTextString = wrappedString
-	p.currentTextObjects[0].Clear()
-	p.currentTextObjects[0].WriteString(wrappedString)
+	p.writeText(wrappedString, GlobalScenes[GlobalCurrentScene])
 }
 
+// addText appends what the player has typed. In the word mode words which haven't been collected are greyed out and
+// refused when the command is entered, see 'handlePlayerInput'.
 func (p *Player) addText(str string, scn *Scene) {
 
 	wrappedString := p.getWrappedString(p.currentTextString + str)
 
 	p.currentTextString = wrappedString
-	p.currentTextObjects[0].Clear()
-	p.currentTextObjects[0].WriteString(wrappedString)
+	p.writeText(wrappedString, scn)
 }
 
 func (p *Player) drawTextInBox(win *pixelgl.Window) {
@@ -124,7 +134,7 @@ func (p *Player) setDefaultAttributes() {
 	p.fontFace = face
 
 	// pixel.ZV is the zero vector representing the orig(in) (i.e. beginning of the line)
-	p.currentTextObjects = append(p.currentTextObjects, text.New(pixel.ZV, text.NewAtlas(face, text.ASCII)))
+	p.currentTextObjects = append(p.currentTextObjects, text.New(pixel.ZV, text.NewAtlas(face, text.ASCII, translationRunes)))
 	p.setTextColor(colornames.Blueviolet)
 
 	p.textBox = new(TextBox)
@@ -0,0 +1,241 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file reloads content files which have been changed while the game is running.
+package scene
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// globalContentModTimes maps the path of every content file to its modification time when it has been loaded, it is
+// where 'WatchContent' starts from.
+var globalContentModTimes map[string]time.Time
+
+// readContentModTimes returns the modification times of all files inside the content directory.
+func readContentModTimes(contentDir string) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			modTimes[filepath.ToSlash(path)] = info.ModTime()
+		}
+		return nil
+	})
+	return modTimes
+}
+
+// contentChanges passes the paths of the changed content files from the goroutine watching them to the game loop, see
+// 'WatchContent'.
+var contentChanges chan []string
+
+// getChangedPaths compares the modification times and returns the paths of the files which are new, changed or have
+// been removed.
+func getChangedPaths(previousModTimes, currentModTimes map[string]time.Time) []string {
+	var changedPaths []string
+	for path, modTime := range currentModTimes {
+		if previousModTime, isKnown := previousModTimes[path]; !isKnown || !previousModTime.Equal(modTime) {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	for path := range previousModTimes {
+		if _, isKnown := currentModTimes[path]; !isKnown {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	sort.Strings(changedPaths)
+	return changedPaths
+}
+
+// getChangedSceneNames returns the names of the scenes whose folders contain the changed files.
+//
+// A changed verb dictionary or shared content file concerns every scene because it changes how scripts are read.
+func getChangedSceneNames(contentDir string, changedPaths []string) (sceneNames []string, isEveryScene bool) {
+	isAdded := make(map[string]bool)
+	for _, path := range changedPaths {
+		relativePath := strings.TrimPrefix(path, filepath.ToSlash(contentDir))
+		folderEnd := strings.Index(relativePath, `/`)
+		if folderEnd < 0 || relativePath[:folderEnd] == SharedContentFolder {
+			isEveryScene = true
+			continue
+		}
+		if sceneName := relativePath[:folderEnd]; !isAdded[sceneName] {
+			isAdded[sceneName] = true
+			sceneNames = append(sceneNames, sceneName)
+		}
+	}
+	return sceneNames, isEveryScene
+}
+
+// WatchContent starts checking the content files for changes every interval so writers see their changes without
+// restarting the game. The files are checked by a goroutine, 'ReloadChangedContent' reloads the changed scenes.
+func WatchContent(interval time.Duration) {
+	if contentChanges != nil {
+		return
+	}
+	contentChanges = make(chan []string)
+	previousModTimes := globalContentModTimes
+	go func() {
+		for range time.Tick(interval) {
+			currentModTimes := readContentModTimes(ContentDir)
+			if changedPaths := getChangedPaths(previousModTimes, currentModTimes); len(changedPaths) > 0 {
+				contentChanges <- changedPaths
+				previousModTimes = currentModTimes
+			}
+		}
+	}()
+}
+
+// ReloadChangedContent reloads the scenes whose files have changed since they have been loaded, see 'WatchContent'.
+//
+// It is meant to be called from the game loop, it returns immediately if nothing has changed. The progress of every
+// scene and 'GlobalCurrentScene' stay as they are.
+func ReloadChangedContent() {
+	var changedPaths []string
+	select {
+	case changedPaths = <-contentChanges:
+	default:
+		return
+	}
+	sceneNames, isEveryScene := getChangedSceneNames(ContentDir, changedPaths)
+	if len(sceneNames) == 0 && !isEveryScene {
+		return
+	}
+
+	if isEveryScene {
+		if err := loadVerbDictionaryFromContent(ContentDir); err != nil {
+			log.Println(err)
+		}
+		sceneNames = nil
+		for sceneName := range GlobalScenes {
+			if !isSpecialScene(sceneName) {
+				sceneNames = append(sceneNames, sceneName)
+			}
+		}
+	}
+
+	for _, sceneName := range sceneNames {
+		if isSpecialScene(sceneName) {
+			continue
+		}
+		buildSceneFromFolder(sceneName)
+		if err := GlobalScenes[sceneName].reloadSceneFiles(ContentDir); err != nil {
+			log.Println(err)
+			continue
+		}
+		log.Printf("Reloaded the scene '%s'", sceneName)
+	}
+}
+
+// reloadSceneFiles loads the scene's files again while keeping its progress, visit count and the state of its objects.
+//
+// If the active section doesn't exist anymore (e.g. because it has been renamed) the scene starts over at
+// '# beginning'.
+func (s *Scene) reloadSceneFiles(contentDir string) error {
+	previousMapConfig := s.mapConfig
+	s.mapConfig = nil
+	liveObjects, previousObjectNames := s.objects, s.objectNames
+
+	err := s.loadSceneFiles(contentDir)
+	if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+		// The scene is played without the invalid entries like when the game has been started, but a map config with
+		// problems (e.g. a syntax error while it's being edited) leaves the previous one in place
+		log.Println(err)
+		for _, problem := range errorList {
+			if problem.File == s.mapConfigPath && previousMapConfig != nil {
+				s.mapConfig = previousMapConfig
+			}
+		}
+	} else if err != nil {
+		s.mapConfig = previousMapConfig
+		return err
+	}
+	if previousMapConfig != nil {
+		s.mapConfig.Visited = previousMapConfig.Visited
+	}
+	if liveObjects != nil {
+		s.mergeObjects(liveObjects, previousObjectNames)
+	}
+
+	if s.script.parsed == nil {
+		return nil
+	}
+	if s.script.parseErr != nil && s.Name == GlobalCurrentScene {
+		s.reportScriptError(s.script.parseErr)
+	}
+
+	section, isFound := s.script.parsed.sectionMap[s.progress]
+	if !isFound {
+		log.Printf("%s: the active section '# %s' doesn't exist anymore, starting over at '# beginning'",
+			s.script.filePath, s.progress)
+		s.progress = `beginning`
+		// The beginning is loaded with the next Enter, see 'scene.OnUpdate'
+		s.script.responseQueue = nil
+		s.resetSectionKeywords()
+		return nil
+	}
+
+	// Lines which are already queued are delivered as they are, the keywords are understood as written now
+	if s.hasKeywords() {
+		s.loadSectionKeywords(section)
+	}
+	return nil
+}
+
+// mergeObjects puts the objects of the reloaded files into the game as it is being played: objects which have been
+// taken or dropped somewhere else stay where they are and attributes which have been changed keep their values. The
+// texts (e.g. of the actions) and conditions are the ones of the files.
+func (s *Scene) mergeObjects(liveObjects map[string]*sceneObject, previousObjectNames map[string]bool) {
+	definitions := s.objects
+	s.objects = liveObjects
+	for objectName := range previousObjectNames {
+		if definitions[objectName] == nil {
+			delete(s.objects, objectName)
+		}
+	}
+
+	for objectName, definition := range definitions {
+		if object := s.findLiveObject(objectName); object != nil {
+			object.updateDefinition(definition)
+		} else {
+			s.objects[objectName] = definition
+		}
+	}
+}
+
+// findLiveObject returns the object of the scene's files wherever it is now, i.e. in the scene, carried by the player
+// or dropped in another scene.
+func (s *Scene) findLiveObject(objectName string) *sceneObject {
+	if object := s.objects[objectName]; object != nil {
+		return object
+	}
+	if object := globalPlayer.items[objectName]; object != nil {
+		return object
+	}
+	for _, otherScene := range GlobalScenes {
+		if otherScene != s && !otherScene.objectNames[objectName] && otherScene.objects[objectName] != nil {
+			return otherScene.objects[objectName]
+		}
+	}
+	return nil
+}
+
+// updateDefinition takes everything but the values of the attributes from the definition, attributes which are new in
+// the definition are added.
+func (o *sceneObject) updateDefinition(definition *sceneObject) {
+	o.ID, o.Scene, o.Name, o.Description = definition.ID, definition.Scene, definition.Name, definition.Description
+	o.Actions, o.Conditions = definition.Actions, definition.Conditions
+	for attribute, value := range definition.Attributes {
+		if _, isSet := o.Attributes[attribute]; isSet {
+			continue
+		}
+		if o.Attributes == nil {
+			o.Attributes = make(map[string]string)
+		}
+		o.Attributes[attribute] = value
+	}
+}
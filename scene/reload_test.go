@@ -0,0 +1,166 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadSceneFiles(t *testing.T) {
+	contentDir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+	os.Mkdir(contentDir+"Island", 0755)
+
+	writeScript := func(content string) {
+		if err := ioutil.WriteFile(contentDir+"Island/script.md", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeScript("# beginning\n" +
+		"`(Swim) > shore`\n" +
+		"\n" +
+		"# shore\n" +
+		"`(Build sandcastle)`\n" +
+		"It collapses.\n")
+	previousModTimes := readContentModTimes(contentDir)
+
+	s := getSceneObjectWithDefaults()
+	s.Name = `Island`
+	if err := s.loadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	s.progress = `shore`
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeScript("# beginning\n" +
+		"`(Swim) > shore`\n" +
+		"\n" +
+		"# shore\n" +
+		"`(Build sandcastle)`\n" +
+		"It stands!\n")
+	// Make sure the change is noticed on file systems with a coarse modification time
+	os.Chtimes(contentDir+"Island/script.md", time.Now(), time.Now().Add(time.Minute))
+	sceneNames, isEveryScene := getChangedSceneNames(contentDir,
+		getChangedPaths(previousModTimes, readContentModTimes(contentDir)))
+	if len(sceneNames) != 1 || sceneNames[0] != `Island` || isEveryScene {
+		t.Fatalf("Expected only 'Island' to have changed but got %v (every scene: %v)", sceneNames, isEveryScene)
+	}
+
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	s.handlePlayerCommand(`build sandcastle`)
+	if s.progress != `shore` || globalNarrator.currentTextString != `It stands!` {
+		t.Fatalf("Expected the changed answer in '# shore' but got %q in '# %s'",
+			globalNarrator.currentTextString, s.progress)
+	}
+
+	writeScript("# beginning\n" +
+		"`(Swim) > beach`\n" +
+		"\n" +
+		"# beach\n" +
+		"Sand everywhere.\n")
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	if s.progress != `beginning` || s.hasKeywords() {
+		t.Fatalf("Expected to start over at '# beginning' after '# shore' has been renamed")
+	}
+
+	// A map config with a syntax error keeps the previous one instead of leaving the scene without one
+	ioutil.WriteFile(contentDir+"Island/mapConfig.json", []byte(`{"look": "Palms."}`), 0644)
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(contentDir+"Island/mapConfig.json", []byte(`{"look": "Palms.",}`), 0644)
+	s.reloadSceneFiles(contentDir)
+	if s.mapConfig == nil || s.mapConfig.Look != `Palms.` {
+		t.Errorf("Expected the previous map config to be kept but got %v", s.mapConfig)
+	}
+
+	// Removed files are changes, too
+	previousModTimes = readContentModTimes(contentDir)
+	os.Remove(contentDir + "Island/mapConfig.json")
+	changedPaths := getChangedPaths(previousModTimes, readContentModTimes(contentDir))
+	if len(changedPaths) != 1 || changedPaths[0] != filepath.ToSlash(contentDir+"Island/mapConfig.json") {
+		t.Errorf("Expected the removed map config to be reported but got %v", changedPaths)
+	}
+}
+
+func TestReloadKeepsObjectState(t *testing.T) {
+	previousScenes, previousCurrentScene := GlobalScenes, GlobalCurrentScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene = previousScenes, previousCurrentScene
+		globalPlayer.items = nil
+	}()
+	globalPlayer.items = nil
+
+	contentDir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+	os.Mkdir(contentDir+"Island", 0755)
+
+	writeFile := func(filename, content string) {
+		if err := ioutil.WriteFile(contentDir+"Island/"+filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCupboard := func(unlockedText string) {
+		writeFile("cupboard.json", `{
+			"id": "cupboard1",
+			"lock_state": "locked",
+			"actions": {"open": {"lock_state=locked": "It is locked.", "lock_state=unlocked": "`+unlockedText+`"}},
+			"conditions": {"key1": {"action": "use", "message": "The key turns.",
+				"attribute_change": {"attribute": "lock_state", "value": "unlocked"}}}
+		}`)
+	}
+	writeFile("script.md", "# beginning\nPalms everywhere.\n")
+	writeFile("key.json", `{"id": "key1", "name": "rusty key"}`)
+	writeCupboard("It is empty.")
+
+	s := getSceneObjectWithDefaults()
+	s.Name = `Island`
+	if err := s.loadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	GlobalScenes = map[string]*Scene{`Island`: s}
+	GlobalCurrentScene = `Island`
+
+	s.handlePlayerCommand(`take rusty key`)
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	if s.objects[`key`] != nil || !globalPlayer.hasItem(`key`) {
+		t.Fatal("Expected the key to stay in the player's inventory after reloading")
+	}
+
+	s.handlePlayerCommand(`use rusty key on cupboard`)
+	writeCupboard("A spider lives in it.")
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	s.handlePlayerCommand(`open cupboard`)
+	if globalNarrator.currentTextString != `A spider lives in it.` {
+		t.Fatalf("Expected the cupboard to stay unlocked with the changed text but got %q",
+			globalNarrator.currentTextString)
+	}
+
+	s.handlePlayerCommand(`drop rusty key`)
+	if err := s.reloadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	if s.objects[`key`] == nil || globalPlayer.hasItem(`key`) {
+		t.Fatal("Expected the dropped key to stay in the scene after reloading")
+	}
+}
@@ -0,0 +1,517 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file saves the state of the game to disk and restores it.
+package scene
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SaveDir is the directory the games are saved to
+const SaveDir = `../saves/`
+
+// autosaveSlot is written on every scene change and progress update, the other slots are written by the player.
+const autosaveSlot = 0
+
+// saveSlotCount is the number of slots the player saves to with Ctrl+1 to Ctrl+5
+const saveSlotCount = 5
+
+// globalSaveSlot is the slot which has been saved to or loaded last, Ctrl+S saves there.
+var globalSaveSlot = 1
+
+// saveFileMutex prevents the autosave goroutine and the game loop from writing a save file at the same time
+var saveFileMutex sync.Mutex
+
+// globalSnapshotCount numbers the snapshots taken by 'getSavedGame'.
+var globalSnapshotCount int
+
+// writtenSnapshots maps the path of every save file written to the number of its snapshot so an older snapshot never
+// replaces a newer one (e.g. an autosave written after the game has been saved before quitting). It is guarded by
+// 'saveFileMutex'.
+var writtenSnapshots = make(map[string]int)
+
+// autosaves passes the snapshots of the game to the goroutine writing them, see 'EnableAutosave'
+var autosaves chan *savedGame
+
+// globalPlayTime is the time played before 'globalPlayStartedAt', i.e. the play time of a loaded game.
+var globalPlayTime time.Duration
+
+// globalPlayStartedAt is when the game has been started or loaded. Zero means no game has been started yet.
+var globalPlayStartedAt time.Time
+
+// saveVersion is increased whenever the save file changes in a way older versions can't be read anymore.
+const saveVersion = 1
+
+// globalGameScene is the scene the player has left to open the main menu, 'Continue' returns there.
+var globalGameScene string
+
+// savedGame is the content of a save file.
+//
+// The scripts and map configs aren't saved, only what changes while playing. A game is loaded by reading the content
+// files again and applying the saved state on top, the objects are replaced with the saved ones.
+type savedGame struct {
+	Version      int
+	Metadata     saveMetadata
+	CurrentScene string
+	// Scenes contains every scene except the special ones
+	Scenes map[string]savedScene
+	// Variables are the world's variables set with `[Set: ...]` and `[Add: ...]`
+	Variables map[string]string
+	// Items contains the objects of the carried items
+	Items         map[string]*sceneObject
+	WordInventory []string
+	// NarratorText is the narrator's last text including its markup, it's shown again after loading
+	NarratorText string
+
+	// snapshot is the number of the snapshot, see 'writtenSnapshots'
+	snapshot int
+}
+
+// saveMetadata describes the saved game on the load screen.
+type saveMetadata struct {
+	SavedAt  time.Time
+	Scene    string
+	Section  string
+	PlayTime time.Duration
+	// Snippet is the beginning of the narrator's last text without markup
+	Snippet string
+}
+
+type savedScene struct {
+	Progress string
+	Visited  int
+	// HasKeywords is set if the keywords of the active section have been loaded, see 'hasKeywords'
+	HasKeywords bool
+	// ResponseQueue contains the narrator lines which haven't been delivered yet
+	ResponseQueue   []savedResponse
+	VariantCounters map[string]int
+	// Objects contains the scene's objects with their attributes (e.g. 'lock_state') as they are now, i.e. without the
+	// items taken and with the items dropped here.
+	Objects map[string]*sceneObject
+}
+
+// savedResponse is a 'narratorResponse', the condition is saved as written in the script.
+type savedResponse struct {
+	Text           string
+	ProgressUpdate string
+	Ambience       []savedAmbience
+	Condition      string
+	IsWaitOver     bool
+}
+
+type savedAmbience struct {
+	Kind     string
+	Argument string
+}
+
+// copyObjects copies the objects, see 'sceneObject.copy'.
+func copyObjects(objects map[string]*sceneObject) map[string]*sceneObject {
+	copied := make(map[string]*sceneObject)
+	for objectName, object := range objects {
+		copied[objectName] = object.copy()
+	}
+	return copied
+}
+
+// getGameSceneName returns the scene of the game in progress or an empty string if no game has been started.
+func getGameSceneName() string {
+	if GlobalScenes[GlobalCurrentScene] != nil && !isSpecialScene(GlobalCurrentScene) {
+		return GlobalCurrentScene
+	}
+	return globalGameScene
+}
+
+// startPlayTime starts measuring the play time when the first scene is entered.
+func startPlayTime() {
+	if globalPlayStartedAt.IsZero() {
+		globalPlayStartedAt = globalClock.Now()
+	}
+}
+
+// getPlayTime returns for how long the game has been played, including the play time of a loaded game.
+func getPlayTime() time.Duration {
+	if globalPlayStartedAt.IsZero() {
+		return globalPlayTime
+	}
+	return globalPlayTime + globalClock.Now().Sub(globalPlayStartedAt)
+}
+
+// snippetLength is the number of letters of the narrator's last text shown on the load screen
+const snippetLength = 50
+
+// getSnippet returns the beginning of the text's first line.
+func getSnippet(text string) string {
+	text = strings.TrimSpace(text)
+	if lineEnd := strings.Index(text, "\n"); lineEnd >= 0 {
+		text = text[:lineEnd] + `…`
+	}
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+	return strings.TrimSpace(string([]rune(text)[:snippetLength])) + `…`
+}
+
+// getSavedGame takes a snapshot of every scene. Nothing of the snapshot is shared with the game so it can be written
+// while the game goes on.
+func getSavedGame(currentScene string) *savedGame {
+	globalSnapshotCount++
+	saved := &savedGame{
+		Version: saveVersion,
+		Metadata: saveMetadata{
+			SavedAt:  globalClock.Now(),
+			Scene:    currentScene,
+			Section:  GlobalScenes[currentScene].progress,
+			PlayTime: getPlayTime(),
+			Snippet:  getSnippet(globalNarrator.currentTextString),
+		},
+		CurrentScene:  currentScene,
+		Scenes:        make(map[string]savedScene),
+		Variables:     make(map[string]string),
+		Items:         copyObjects(globalPlayer.items),
+		WordInventory: append([]string(nil), globalPlayer.wordInventory...),
+		NarratorText:  globalNarrator.currentTextSource,
+		snapshot:      globalSnapshotCount,
+	}
+	for name, value := range globalWorld.variables {
+		saved.Variables[name] = value
+	}
+	for sceneName, s := range GlobalScenes {
+		if isSpecialScene(sceneName) {
+			continue
+		}
+		saved.Scenes[sceneName] = s.getSavedScene()
+	}
+	return saved
+}
+
+func (s *Scene) getSavedScene() savedScene {
+	saved := savedScene{
+		Progress:    s.progress,
+		HasKeywords: s.hasKeywords(),
+	}
+	for variantKey, count := range s.script.variantCounters {
+		if saved.VariantCounters == nil {
+			saved.VariantCounters = make(map[string]int)
+		}
+		saved.VariantCounters[variantKey] = count
+	}
+	if s.mapConfig != nil {
+		saved.Visited = s.mapConfig.Visited
+	}
+	for _, response := range s.script.responseQueue {
+		savedResponse := savedResponse{
+			Text:           response.narratorTextLine,
+			ProgressUpdate: response.progressUpdate,
+			IsWaitOver:     response.isWaitOver,
+		}
+		for _, ambienceCmd := range response.ambienceCmdSlice {
+			savedResponse.Ambience = append(savedResponse.Ambience, savedAmbience{ambienceCmd.kind, ambienceCmd.argument})
+		}
+		if response.condition != nil {
+			savedResponse.Condition = response.condition.source
+		}
+		saved.ResponseQueue = append(saved.ResponseQueue, savedResponse)
+	}
+	saved.Objects = copyObjects(s.objects)
+	return saved
+}
+
+// writeSavedGame writes the save file. It is written next to the old one first so a crash while writing doesn't
+// destroy the previous save. Nothing is written if the file already contains a newer snapshot.
+func writeSavedGame(path string, saved *savedGame) error {
+	saveFileMutex.Lock()
+	defer saveFileMutex.Unlock()
+	if saved.snapshot < writtenSnapshots[path] {
+		return nil
+	}
+
+	jsonBytes, err := json.MarshalIndent(saved, ``, `    `)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+`.tmp`, jsonBytes, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+`.tmp`, path); err != nil {
+		return err
+	}
+	writtenSnapshots[path] = saved.snapshot
+	return nil
+}
+
+// getSaveSlotPath returns the path of the slot's save file.
+func getSaveSlotPath(saveDir string, slot int) string {
+	if slot == autosaveSlot {
+		return saveDir + `autosave.json`
+	}
+	return fmt.Sprintf("%sslot%d.json", saveDir, slot)
+}
+
+// saveGameToSlot writes the game in progress to the slot. Nothing is written if no game has been started.
+func saveGameToSlot(saveDir string, slot int) error {
+	sceneName := getGameSceneName()
+	if sceneName == `` {
+		return nil
+	}
+	return writeSavedGame(getSaveSlotPath(saveDir, slot), getSavedGame(sceneName))
+}
+
+// SaveGame writes the game in progress to the autosave slot and waits until it is written, e.g. before quitting.
+// 'Continue' in the main menu loads it again. An older snapshot the autosave goroutine is still busy with doesn't
+// replace it.
+func SaveGame() error {
+	return saveGameToSlot(SaveDir, autosaveSlot)
+}
+
+// EnableAutosave starts saving the game to the autosave slot on every scene change and progress update.
+//
+// The files are written by a goroutine so the game loop never waits for the disk. If the game moves on before a
+// snapshot has been written only the newer snapshot is written.
+func EnableAutosave() {
+	if autosaves != nil {
+		return
+	}
+	autosaves = make(chan *savedGame, 1)
+	go func() {
+		for saved := range autosaves {
+			if err := writeSavedGame(getSaveSlotPath(SaveDir, autosaveSlot), saved); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+}
+
+// autosave hands a snapshot of the game to the autosave goroutine without waiting for it.
+func autosave() {
+	sceneName := getGameSceneName()
+	if autosaves == nil || sceneName == `` {
+		return
+	}
+	saved := getSavedGame(sceneName)
+	for {
+		select {
+		case autosaves <- saved:
+			return
+		default:
+			// The waiting snapshot hasn't been written yet and is outdated now
+			select {
+			case <-autosaves:
+			default:
+			}
+		}
+	}
+}
+
+// readSavedGame reads a save file written by the current version of the game.
+func readSavedGame(path string) (*savedGame, error) {
+	jsonBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var saved savedGame
+	if err := json.Unmarshal(jsonBytes, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if saved.Version != saveVersion {
+		return nil, fmt.Errorf("%s: the game has been saved in version %d but only version %d can be loaded",
+			path, saved.Version, saveVersion)
+	}
+	if GlobalScenes[saved.CurrentScene] == nil || isSpecialScene(saved.CurrentScene) {
+		return nil, fmt.Errorf("%s: the game has been saved in the scene '%s' which doesn't exist", path,
+			saved.CurrentScene)
+	}
+	return &saved, nil
+}
+
+// loadGame replaces the game in progress with the one saved at path.
+func loadGame(path string) error {
+	saved, err := readSavedGame(path)
+	if err != nil {
+		return err
+	}
+	if err := restoreGame(saved, true); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	globalPlayTime = saved.Metadata.PlayTime
+	globalPlayStartedAt = globalClock.Now()
+	// The commands of the previous game can't be undone in the loaded one
+	globalUndoSnapshots = nil
+	return nil
+}
+
+// restoreGame replaces the game in progress with the saved one and shows the narrator's text of the saved game again.
+//
+// A game loaded from disk reads the content files again (isContentReloaded), a snapshot of the game in progress (see
+// 'undo') is applied to the content in memory.
+func restoreGame(saved *savedGame, isContentReloaded bool) error {
+	for sceneName, s := range GlobalScenes {
+		if isSpecialScene(sceneName) {
+			continue
+		}
+		// Scenes which have been added since saving start at the beginning
+		if err := s.restoreScene(saved.Scenes[sceneName], isContentReloaded); err != nil {
+			return err
+		}
+	}
+
+	globalWorld.variables = saved.Variables
+	if globalWorld.variables == nil {
+		globalWorld.variables = make(map[string]string)
+	}
+	globalPlayer.items = saved.Items
+	globalPlayer.wordInventory = saved.WordInventory
+	globalPlayer.setText(``)
+
+	GlobalCurrentScene = saved.CurrentScene
+	globalGameScene = saved.CurrentScene
+	// The scene has been entered before saving, entering it again would count a visit and deliver the next line
+	globalPreviousScene = saved.CurrentScene
+
+	s := GlobalScenes[GlobalCurrentScene]
+	s.noteActivity()
+	if saved.NarratorText != `` {
+		globalNarrator.setTextLetterByLetter(saved.NarratorText, s)
+	}
+	s.updateHintTexts()
+	return nil
+}
+
+// restoreScene applies the saved state to the scene, if isContentReloaded its files are loaded again before.
+func (s *Scene) restoreScene(saved savedScene, isContentReloaded bool) error {
+	if isContentReloaded {
+		s.mapConfig = nil
+		// Problems with the content files have been logged when the game has been started
+		err := s.loadSceneFiles(ContentDir)
+		if _, isErrorList := err.(ScriptErrorList); !isErrorList && err != nil {
+			return err
+		}
+	}
+	s.mapConfig.Visited = saved.Visited
+
+	s.progress = saved.Progress
+	if s.progress == `` {
+		s.progress = `beginning`
+	}
+	s.script.responseQueue = nil
+	s.resetSectionKeywords()
+	s.script.variantCounters = saved.VariantCounters
+	s.script.waitUntil, s.script.autoAdvanceAt = time.Time{}, time.Time{}
+	s.script.idleResponses = nil
+
+	for _, savedResponse := range saved.ResponseQueue {
+		response := narratorResponse{
+			narratorTextLine: savedResponse.Text,
+			progressUpdate:   savedResponse.ProgressUpdate,
+			isWaitOver:       savedResponse.IsWaitOver,
+		}
+		for _, ambience := range savedResponse.Ambience {
+			response.ambienceCmdSlice = append(response.ambienceCmdSlice,
+				&ambienceDirective{kind: ambience.Kind, argument: ambience.Argument})
+		}
+		if savedResponse.Condition != `` {
+			condition, err := parseCondition(savedResponse.Condition)
+			if err != nil {
+				return err
+			}
+			response.condition = condition
+		}
+		s.script.responseQueue = append(s.script.responseQueue, response)
+	}
+
+	// Scenes which have been added since saving keep the objects of their files
+	if saved.Objects != nil {
+		s.objects = saved.Objects
+	}
+
+	if s.script.parsed == nil {
+		return nil
+	}
+	section, isFound := s.script.parsed.sectionMap[s.progress]
+	if !isFound {
+		log.Printf("%s: the section '# %s' of the saved game doesn't exist anymore, starting over at '# beginning'",
+			s.script.filePath, s.progress)
+		s.progress = `beginning`
+		s.script.responseQueue = nil
+		return nil
+	}
+	if saved.HasKeywords {
+		s.loadSectionKeywords(section)
+	}
+	return nil
+}
+
+// saveSlot is a slot as it is listed on the load screen.
+type saveSlot struct {
+	slot int
+	path string
+	// metadata is nil if nothing has been saved to the slot
+	metadata *saveMetadata
+}
+
+// readSaveSlots returns the autosave slot followed by the numbered slots.
+func readSaveSlots(saveDir string) []saveSlot {
+	var saveSlots []saveSlot
+	for slot := autosaveSlot; slot <= saveSlotCount; slot++ {
+		saveSlot := saveSlot{slot: slot, path: getSaveSlotPath(saveDir, slot)}
+		if saved, err := readSavedGame(saveSlot.path); err == nil {
+			saveSlot.metadata = &saved.Metadata
+		} else if !os.IsNotExist(err) {
+			log.Println(err)
+		}
+		saveSlots = append(saveSlots, saveSlot)
+	}
+	return saveSlots
+}
+
+// getLatestSaveSlot returns the slot which has been saved to last or nil if there is no saved game.
+func getLatestSaveSlot(saveSlots []saveSlot) *saveSlot {
+	var latest *saveSlot
+	for i, saveSlot := range saveSlots {
+		if saveSlot.metadata != nil && (latest == nil || saveSlot.metadata.SavedAt.After(latest.metadata.SavedAt)) {
+			latest = &saveSlots[i]
+		}
+	}
+	return latest
+}
+
+// loadGameFromSlot loads the slot's game, Ctrl+S saves to this slot afterwards.
+func loadGameFromSlot(saveSlot *saveSlot) error {
+	if err := loadGame(saveSlot.path); err != nil {
+		return err
+	}
+	if saveSlot.slot != autosaveSlot {
+		globalSaveSlot = saveSlot.slot
+	}
+	return nil
+}
+
+// errNoGameToContinue is returned by 'continueGame' if no game has been started or saved yet.
+var errNoGameToContinue = errors.New("there is no game in progress and no saved game")
+
+// continueGame returns to the game in progress. If no game has been started yet the game saved last is loaded.
+func continueGame() error {
+	if globalGameScene != `` {
+		GlobalCurrentScene = globalGameScene
+		return nil
+	}
+	latest := getLatestSaveSlot(readSaveSlots(SaveDir))
+	if latest == nil {
+		return errNoGameToContinue
+	}
+	return loadGameFromSlot(latest)
+}
@@ -0,0 +1,182 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadGame(t *testing.T) {
+	previousScenes, previousCurrentScene, previousPreviousScene := GlobalScenes, GlobalCurrentScene, globalPreviousScene
+	previousVariables, previousGameScene := globalWorld.variables, globalGameScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalPreviousScene = previousScenes, previousCurrentScene, previousPreviousScene
+		globalWorld.variables, globalGameScene = previousVariables, previousGameScene
+		globalPlayer.items = nil
+	}()
+
+	saveDir, err := ioutil.TempDir("", "save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(saveDir)
+	savePath := getSaveSlotPath(saveDir+"/", 1)
+
+	LoadFilesToSceneMap()
+	globalWorld.variables = map[string]string{`mood`: `curious`}
+	GlobalCurrentScene, globalPreviousScene = `Beach`, `Beach`
+	beach := GlobalScenes[`Beach`]
+	beach.mapConfig.Visited = 2
+	beach.objects[`oldCupboard`].Attributes[`lock_state`] = `unlocked`
+	globalPlayer.items = map[string]*sceneObject{`cupboardKey`: {Name: `old cupboard key`}}
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	// Stop in the middle of the beginning, one line has been shown and two are left
+	beach.deliverNextResponse()
+	shownText := globalNarrator.currentTextString
+
+	if err := writeSavedGame(savePath, getSavedGame(GlobalCurrentScene)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Play on and change everything the save file contains
+	for beach.deliverNextResponse() {
+	}
+	beach.handlePlayerCommand(`inspect reflection`)
+	beach.mapConfig.Visited = 5
+	beach.objects[`oldCupboard`].Attributes[`lock_state`] = `broken`
+	globalWorld.variables = map[string]string{}
+	globalPlayer.items = nil
+	GlobalCurrentScene = `MainMenu`
+
+	if err := loadGame(savePath); err != nil {
+		t.Fatal(err)
+	}
+	beach = GlobalScenes[`Beach`]
+	if GlobalCurrentScene != `Beach` || beach.progress != `beginning` {
+		t.Errorf("Expected to be at 'Beach#beginning' but got '%s#%s'", GlobalCurrentScene, beach.progress)
+	}
+	if beach.mapConfig.Visited != 2 || beach.objects[`oldCupboard`].Attributes[`lock_state`] != `unlocked` {
+		t.Errorf("Expected 2 visits and an unlocked cupboard but got %d visits and '%v'", beach.mapConfig.Visited,
+			beach.objects[`oldCupboard`].Attributes[`lock_state`])
+	}
+	if globalWorld.variables[`mood`] != `curious` || !globalPlayer.hasItem(`cupboardKey`) {
+		t.Errorf("Expected the variables and the inventory to be restored but got %v and %v", globalWorld.variables,
+			globalPlayer.items)
+	}
+	if globalNarrator.currentTextString != shownText {
+		t.Errorf("Expected the narrator to show '%s' again but got '%s'", shownText, globalNarrator.currentTextString)
+	}
+	if len(beach.script.responseQueue) != 2 {
+		t.Fatalf("Expected the two remaining lines of the beginning to be queued but got %d lines",
+			len(beach.script.responseQueue))
+	}
+	beach.deliverNextResponse()
+	beach.deliverNextResponse()
+	if !strings.HasPrefix(globalNarrator.currentTextString, `As the sunlight falls`) || !beach.hasKeywords() {
+		t.Errorf("Expected the beginning to continue where it has been saved but got '%s'",
+			globalNarrator.currentTextString)
+	}
+
+	// A section which has been renamed since saving starts the scene over, whether it waited for a command or not
+	saved := getSavedGame(`Beach`)
+	savedBeach := saved.Scenes[`Beach`]
+	savedBeach.Progress, savedBeach.HasKeywords = `renamed_section`, false
+	saved.Scenes[`Beach`] = savedBeach
+	if err := restoreGame(saved, true); err != nil {
+		t.Fatal(err)
+	}
+	beach = GlobalScenes[`Beach`]
+	if beach.progress != `beginning` || len(beach.script.responseQueue) != 0 {
+		t.Errorf("Expected to start over at '# beginning' but got '# %s'", beach.progress)
+	}
+	if err := beach.loadActiveSection(); err != nil {
+		t.Errorf("Expected the beginning to be loadable but got %v", err)
+	}
+
+	ioutil.WriteFile(savePath, []byte(`{"Version": 999, "CurrentScene": "Beach"}`), 0644)
+	if err := loadGame(savePath); err == nil || !strings.Contains(err.Error(), `saved in version 999`) {
+		t.Errorf("Expected save files of other versions to be refused but got '%v'", err)
+	}
+}
+
+func TestSaveSlots(t *testing.T) {
+	previousScenes, previousCurrentScene, previousGameScene := GlobalScenes, GlobalCurrentScene, globalGameScene
+	testClock := &fakeClock{now: time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)}
+	globalClock = testClock
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalGameScene = previousScenes, previousCurrentScene, previousGameScene
+		globalClock = systemClock{}
+		globalPlayTime, globalPlayStartedAt = 0, time.Time{}
+	}()
+
+	saveDir, err := ioutil.TempDir("", "slots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(saveDir)
+	saveDir += "/"
+
+	LoadFilesToSceneMap()
+	GlobalCurrentScene = `Beach`
+	beach := GlobalScenes[`Beach`]
+	beach.progress = `get_compass`
+	globalNarrator.setTextLetterByLetter(`It was glass that reflected sunlight into your eyes. Glass that belonged to `+
+		`a little device.`, beach)
+	globalPlayTime, globalPlayStartedAt = 0, time.Time{}
+	startPlayTime()
+	testClock.advance(time.Hour + 5*time.Minute + 9*time.Second)
+	if err := saveGameToSlot(saveDir, 2); err != nil {
+		t.Fatal(err)
+	}
+	testClock.advance(time.Minute)
+	if err := saveGameToSlot(saveDir, autosaveSlot); err != nil {
+		t.Fatal(err)
+	}
+
+	saveSlots := readSaveSlots(saveDir)
+	if len(saveSlots) != saveSlotCount+1 || saveSlots[1].metadata != nil {
+		t.Fatalf("Expected the autosave and %d slots of which slot 1 is empty but got %v", saveSlotCount, saveSlots)
+	}
+	expectedText := "Slot 2: Beach # get_compass (played 1:05:09, saved 2021-03-14 16:14)\n" +
+		"It was glass that reflected sunlight into your eye…"
+	if text := saveSlots[2].getText(); text != expectedText {
+		t.Errorf("Expected the slot to be listed as\n%s\nbut got\n%s", expectedText, text)
+	}
+	if latest := getLatestSaveSlot(saveSlots); latest == nil || latest.slot != autosaveSlot {
+		t.Errorf("Expected the autosave to be the latest save but got %v", latest)
+	}
+
+	// The autosave goroutine isn't running so the snapshots pile up, only the latest one is kept
+	autosaves = make(chan *savedGame, 1)
+	defer func() {
+		autosaves = nil
+	}()
+	autosave()
+	beach.progress = `got_compass`
+	autosave()
+	if saved := <-autosaves; saved.Metadata.Section != `got_compass` {
+		t.Errorf("Expected the outdated autosave to be dropped but got the section '%s'", saved.Metadata.Section)
+	}
+
+	// An autosave which is written after the game has been saved (e.g. when quitting) doesn't replace the newer save
+	beach.progress = `beginning`
+	outdated := getSavedGame(`Beach`)
+	beach.progress = `got_compass`
+	if err := saveGameToSlot(saveDir, autosaveSlot); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSavedGame(getSaveSlotPath(saveDir, autosaveSlot), outdated); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := readSavedGame(getSaveSlotPath(saveDir, autosaveSlot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Metadata.Section != `got_compass` {
+		t.Errorf("Expected the newer save to be kept but got the section '%s'", saved.Metadata.Section)
+	}
+}
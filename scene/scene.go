@@ -3,9 +3,13 @@
 package scene
 
 import (
+	"fmt"
 	"image/color"
+	"log"
+	"math/rand"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/image/colornames"
 
@@ -34,6 +38,9 @@ var globalPlayer Player
 var globalNarrator Narrator
 var globalWindow *pixelgl.Window
 
+// globalRandom is used for everything left to chance so a playthrough can be repeated with the same seed.
+var globalRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+
 type threadSafeBool struct {
 	value bool
 	sync.Mutex
@@ -71,25 +78,65 @@ type Scene struct {
 	progress      string
 	mapConfigPath string
 	mapConfig     *MapConfig
-	objects       map[string]map[string]interface{}
+	objects       map[string]*sceneObject
+	// objectNames are the objects which the scene's files define, 'objects' contains the ones which are in the scene
+	// now
+	objectNames map[string]bool
 }
 
 // Script groups all info from the (markdown) script to make it available to functions within a scene
 type Script struct {
 	filePath    string
 	fileContent string
+	// parsed contains the sections of the script file, parseErr the problems found while parsing it.
+	parsed   *scriptFile
+	parseErr error
 	// responseQueue contains the responses that still need to be delivered before player commands become active again.
 	responseQueue []narratorResponse
-	// keywordResponseMap contains a map from the player commands that are understood to a slice of narratorResponses.
-	keywordResponseMap map[string][]narratorResponse
+	// keywordResponseMap contains a map from the player commands that are understood (normalized, see
+	// 'normalizeCommand') to their alternative responses. The first alternative whose condition is met is delivered.
+	keywordResponseMap map[string][]keywordAlternative
+	// catchAllAlternatives answer commands the section doesn't understand otherwise, see 'handleUnknownCommand'
+	catchAllAlternatives []keywordAlternative
+	// variantCounters counts how often a keyword with variants has been answered so cycling continues after leaving the
+	// section.
+	variantCounters map[string]int
+
+	// waitUntil and autoAdvanceAt are set by the timed directives, see 'advanceTimedScript'. Zero means not set.
+	waitUntil     time.Time
+	autoAdvanceAt time.Time
+	// idleResponses are the section's `[Idle: ...]` lines, they are delivered once the player is idle long enough
+	idleResponses []narratorResponse
+	// lastActivity is when the player typed or pressed Enter for the last time
+	lastActivity time.Time
+	// deliveredIdleResponses contains the indices of the idle responses delivered since the last activity
+	deliveredIdleResponses map[int]bool
+}
+
+// keywordAlternative contains the responses to a player command which are delivered if the condition is met.
+//
+// There is one response slice per variant, see keywordBlock for the variant modes.
+type keywordAlternative struct {
+	// keyword is written like in the script to be able to show it to the player
+	keyword     string
+	condition   *scriptCondition
+	variants    [][]narratorResponse
+	variantMode string
+	// variantKey identifies the keyword in 'Script.variantCounters'
+	variantKey string
 }
 
 // narratorResponse groups the narrator text with it's ambience commands
-// A player cmd is mapped onto a struct containing narrator text lines, ambience directives or progress updates.
+// A player cmd is mapped onto a slice of these structs containing narrator text lines, ambience directives or, as the
+// last element, a progress update.
 type narratorResponse struct {
 	narratorTextLine string
 	progressUpdate   string
-	ambienceCmdSlice []string
+	ambienceCmdSlice []*ambienceDirective
+	// condition has to be met when the response is delivered, otherwise it is skipped
+	condition *scriptCondition
+	// isWaitOver is set once the response's `[Wait: ...]` directive has paused the script
+	isWaitOver bool
 }
 
 // This is called once when the package is imported for the first time
@@ -98,6 +145,11 @@ func init() {
 	globalNarrator.setDefaultAttributes()
 }
 
+// SeedRandom makes everything left to chance (e.g. random keyword variants) repeatable.
+func SeedRandom(seed int64) {
+	globalRandom = rand.New(rand.NewSource(seed))
+}
+
 // SetWindowForAllScenes initializes the global window variable for all scenes
 func SetWindowForAllScenes(win *pixelgl.Window) {
 	globalWindow = win
@@ -152,13 +204,13 @@ func (s *Scene) initHintText() {
 		panic(err)
 	}
 
-	atlas := text.NewAtlas(face, text.ASCII)
+	atlas := text.NewAtlas(face, text.ASCII, translationRunes)
 
 	s.narratorBoxHint = &controltext.SafeText{
 		Text: text.New(pixel.ZV, atlas),
 	}
 	s.narratorBoxHint.Color = colornames.Gray
-	s.narratorBoxHint.WriteString("Press Enter to continue.")
+	s.narratorBoxHint.WriteString(translate("Press Enter to continue."))
 
 	s.playerBoxHint = &controltext.SafeText{
 		Text: text.New(pixel.ZV, atlas),
@@ -176,7 +228,7 @@ func getSceneObjectWithDefaults() *Scene {
 	defaultScene := &Scene{
 		bgColor:   colornames.White,
 		textColor: colornames.Black,
-		atlas:     text.NewAtlas(face, text.ASCII),
+		atlas:     text.NewAtlas(face, text.ASCII, translationRunes),
 
 		trackMap: make(map[int]*effects.Volume),
 
@@ -197,22 +249,64 @@ func getSceneObjectWithDefaults() *Scene {
 func handleBackspace(win *pixelgl.Window) {
 	if len(globalPlayer.currentTextString) > 0 &&
 		(win.JustPressed(pixelgl.KeyBackspace) || win.Repeated(pixelgl.KeyBackspace)) {
-		globalPlayer.setText(globalPlayer.currentTextString[:len(globalPlayer.currentTextString)-1])
+		// The input may end with a letter of the translations which takes more than one byte, e.g. 'ä'
+		_, lastRuneSize := utf8.DecodeLastRuneInString(globalPlayer.currentTextString)
+		globalPlayer.setText(globalPlayer.currentTextString[:len(globalPlayer.currentTextString)-lastRuneSize])
 	}
 }
 
+// hasKeywords returns whether the active section waits for player commands.
+func (s *Scene) hasKeywords() bool {
+	return len(s.script.keywordResponseMap) > 0 || len(s.script.catchAllAlternatives) > 0
+}
+
+// resetSectionKeywords forgets the keywords of the active section, it is loaded again when the player is asked for the
+// next command (see 'scene.OnUpdate').
+func (s *Scene) resetSectionKeywords() {
+	s.script.keywordResponseMap = nil
+	s.script.catchAllAlternatives = nil
+}
+
 func (s *Scene) updateHintTexts() {
-	if len(s.script.responseQueue) == 0 && len(s.script.keywordResponseMap) > 0 {
+	if len(s.script.responseQueue) == 0 && s.hasKeywords() {
 		s.playerBoxHint.Clear()
 		s.narratorBoxHint.Clear()
-		s.playerBoxHint.WriteString("Write a command and press Enter.")
+		s.playerBoxHint.WriteString(translate("Write a command and press Enter."))
 	} else {
 		s.narratorBoxHint.Clear()
 		s.playerBoxHint.Clear()
-		s.narratorBoxHint.WriteString("Press Enter to continue.")
+		s.narratorBoxHint.WriteString(translate("Press Enter to continue."))
 	}
 }
 
+// countVisit increments the number of times the scene has been entered.
+//
+// Coming back from the main menu doesn't count as a new visit.
+func (s *Scene) countVisit() {
+	if s.mapConfig == nil {
+		return
+	}
+	if s.mapConfig.Visited == 0 || !isSpecialScene(globalPreviousScene) {
+		s.mapConfig.Visited++
+	}
+}
+
+// saveSlotKeys maps the save slots to the keys saving there together with Ctrl. Ctrl+S saves to the slot used last.
+var saveSlotKeys = map[int]pixelgl.Button{
+	1: pixelgl.Key1, 2: pixelgl.Key2, 3: pixelgl.Key3, 4: pixelgl.Key4, 5: pixelgl.Key5,
+}
+
+// saveToSlot saves the game and tells the player in the hint above the narrator's text.
+func (s *Scene) saveToSlot(slot int) {
+	if err := saveGameToSlot(SaveDir, slot); err != nil {
+		log.Println(err)
+		return
+	}
+	globalSaveSlot = slot
+	s.narratorBoxHint.Clear()
+	s.narratorBoxHint.WriteString(fmt.Sprintf(translate("The game has been saved in slot %d."), slot))
+}
+
 // OnUpdate listens and processes player input on every frame update.
 func (s *Scene) OnUpdate(win *pixelgl.Window) {
 
@@ -220,6 +314,9 @@ func (s *Scene) OnUpdate(win *pixelgl.Window) {
 	case "MainMenu":
 		s.onUpdateMainMenu(win)
 		return
+	case `LoadMenu`:
+		s.onUpdateLoadMenu(win)
+		return
 	case `Demo`:
 		s.onUpdateDemo(win)
 		return
@@ -235,23 +332,63 @@ func (s *Scene) OnUpdate(win *pixelgl.Window) {
 	}
 
 	if win.Pressed(pixelgl.KeyLeftControl) && win.JustPressed(pixelgl.KeyQ) {
+		if err := SaveGame(); err != nil {
+			log.Println(err)
+		}
 		win.SetClosed(true)
 	}
+	if win.Pressed(pixelgl.KeyLeftControl) {
+		if win.JustPressed(pixelgl.KeyZ) {
+			undo()
+			return
+		}
+		if win.JustPressed(pixelgl.KeyS) {
+			s.saveToSlot(globalSaveSlot)
+			return
+		}
+		for slot, key := range saveSlotKeys {
+			if win.JustPressed(key) {
+				s.saveToSlot(slot)
+				return
+			}
+		}
+	}
 	if win.JustPressed(pixelgl.KeyEscape) {
+		globalGameScene = GlobalCurrentScene
 		GlobalCurrentScene = "MainMenu"
 	}
 	handleBackspace(win)
+	if s.advanceTimedScript() {
+		s.updateHintTexts()
+		return
+	}
+	if s.isWaiting() {
+		return
+	}
 	if win.JustPressed(pixelgl.KeyEnter) || (globalPreviousScene != GlobalCurrentScene) {
+		s.noteActivity()
+		isSceneSwitch := globalPreviousScene != GlobalCurrentScene
+		if isSceneSwitch {
+			s.countVisit()
+			startPlayTime()
+		}
 		globalPreviousScene = GlobalCurrentScene
-		if len(s.script.responseQueue) == 0 && len(s.script.keywordResponseMap) == 0 {
-			s.parseScriptFile()
+		if len(s.script.responseQueue) == 0 && !s.hasKeywords() {
+			if err := s.loadActiveSection(); err != nil {
+				s.reportScriptError(err)
+				return
+			}
 		}
 		s.executeScriptFromQueue()
+		if isSceneSwitch {
+			autosave()
+		}
 
 		s.updateHintTexts()
 	}
 
 	if len(s.script.responseQueue) == 0 && len(win.Typed()) > 0 {
+		s.noteActivity()
 		globalPlayer.addText(win.Typed(), s)
 	}
 }
@@ -263,6 +400,9 @@ func (s *Scene) Draw(win *pixelgl.Window, start time.Time) {
 	case `MainMenu`:
 		s.drawMainMenu(win)
 		return
+	case `LoadMenu`:
+		s.drawLoadMenu(win)
+		return
 	case `Demo`:
 		s.drawDemo(win, start)
 		return
@@ -283,4 +423,7 @@ func (s *Scene) Draw(win *pixelgl.Window, start time.Time) {
 
 	globalPlayer.drawTextInBox(win)
 	globalNarrator.drawTextInBox(win)
+	if s.isWordMode() {
+		s.drawWordPanel(win)
+	}
 }
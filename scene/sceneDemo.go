@@ -35,6 +35,10 @@ func (s *Scene) initDemo() {
 		Text: text.New(pixel.ZV, s.atlas),
 	}
 
+	// There is no sound device while playing in a terminal
+	if globalTerminal != nil {
+		return
+	}
 	var trackArray = [4]string{"Celesta.ogg", "Choir.ogg", "Harp.ogg", "Strings.ogg"}
 	var trackPath = "../assets/"
 	s.trackMap = make(map[int]*effects.Volume)
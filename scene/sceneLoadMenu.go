@@ -0,0 +1,111 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the load screen listing the save slots.
+package scene
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/3ter/iMagine/fileio"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// globalLoadMenuSlots are read when the load screen is opened
+var globalLoadMenuSlots []saveSlot
+
+// globalLoadMenuSelection is the index of the selected slot in 'globalLoadMenuSlots'
+var globalLoadMenuSelection int
+
+func (s *Scene) initLoadMenu() {
+	s.bgColor = colornames.Black
+	s.textColor = colornames.White
+}
+
+// openLoadMenu reads the save slots and shows them with the slot saved to last being selected.
+func openLoadMenu() {
+	globalLoadMenuSlots = readSaveSlots(SaveDir)
+	globalLoadMenuSelection = 0
+	if latest := getLatestSaveSlot(globalLoadMenuSlots); latest != nil {
+		for i, saveSlot := range globalLoadMenuSlots {
+			if saveSlot.slot == latest.slot {
+				globalLoadMenuSelection = i
+			}
+		}
+	}
+	GlobalCurrentScene = `LoadMenu`
+}
+
+// getPlayTimeText formats the play time like '1:05:09'.
+func getPlayTimeText(metadata *saveMetadata) string {
+	seconds := int(metadata.PlayTime.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// getText returns the slot's lines on the load screen, the saved scene and section followed by the narrator's text.
+func (s *saveSlot) getText() string {
+	title := fmt.Sprintf(translate("Slot %d"), s.slot)
+	if s.slot == autosaveSlot {
+		title = translate("Autosave")
+	}
+	if s.metadata == nil {
+		return title + `: ` + translate("empty")
+	}
+	return fmt.Sprintf("%s: %s # %s ("+translate("played %s, saved %s")+")\n%s", title, s.metadata.Scene,
+		s.metadata.Section, getPlayTimeText(s.metadata), s.metadata.SavedAt.Format(`2006-01-02 15:04`),
+		s.metadata.Snippet)
+}
+
+func (s *Scene) drawLoadMenu(win *pixelgl.Window) {
+	win.Clear(s.bgColor)
+
+	slotTextVerticalOffset := 70 // pixels
+
+	regularFace := fileio.TtfFromBytesMust(goregular.TTF, 16)
+	boldFace := fileio.TtfFromBytesMust(gobold.TTF, 16)
+	atlasRegular := text.NewAtlas(regularFace, text.ASCII, translationRunes)
+	atlasBold := text.NewAtlas(boldFace, text.ASCII, translationRunes)
+
+	top := float64(slotTextVerticalOffset*len(globalLoadMenuSlots)) / 2
+	for i, saveSlot := range globalLoadMenuSlots {
+		txt := text.New(pixel.ZV, atlasRegular)
+		if i == globalLoadMenuSelection {
+			txt = text.New(pixel.ZV, atlasBold)
+		}
+		txt.WriteString(saveSlot.getText())
+		centerTextMatrix := pixel.IM.Moved(win.Bounds().Center().Sub(txt.Bounds().Center()))
+		verticalAdjustVector := pixel.V(0, top-float64(slotTextVerticalOffset*i))
+		txt.Draw(win, centerTextMatrix.Moved(verticalAdjustVector))
+	}
+}
+
+// onUpdateLoadMenu selects a slot with the arrow keys and loads it with Enter. Escape returns to the main menu.
+func (s *Scene) onUpdateLoadMenu(win *pixelgl.Window) {
+
+	if win.JustPressed(pixelgl.KeyDown) && globalLoadMenuSelection < len(globalLoadMenuSlots)-1 {
+		globalLoadMenuSelection++
+	}
+	if win.JustPressed(pixelgl.KeyUp) && globalLoadMenuSelection > 0 {
+		globalLoadMenuSelection--
+	}
+	if win.JustPressed(pixelgl.KeyEscape) {
+		GlobalCurrentScene = `MainMenu`
+		return
+	}
+
+	if win.JustPressed(pixelgl.KeyEnter) {
+		saveSlot := &globalLoadMenuSlots[globalLoadMenuSelection]
+		if saveSlot.metadata == nil {
+			return
+		}
+		if err := loadGameFromSlot(saveSlot); err != nil {
+			log.Println(err)
+		}
+	}
+}
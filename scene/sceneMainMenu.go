@@ -3,6 +3,8 @@
 package scene
 
 import (
+	"log"
+
 	"github.com/3ter/iMagine/fileio"
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
@@ -20,10 +22,16 @@ type mainMenuItem struct {
 
 var globalMenuItems = []*mainMenuItem{
 	{"Demo", "Demo", "selected"},
+	{"Continue", "Continue", "unselected"},
 	{"Start", "Beach", "unselected"},
+	{"Load", "Load", "unselected"},
 	{"Quit", "Quit", "unselected"},
 }
 
+// globalMainMenuMessage is shown below the menu items if a menu item can't be used (e.g. 'Continue' without a game to
+// continue), it's cleared with the next key press.
+var globalMainMenuMessage string
+
 func (s *Scene) initMainMenu() {
 	s.bgColor = colornames.Black
 	s.textColor = colornames.White
@@ -36,7 +44,7 @@ func returnMenuTexts(atlasRegular, atlasBold *text.Atlas) []*text.Text {
 		if menuItem.State == "selected" {
 			txt = text.New(pixel.ZV, atlasBold)
 		}
-		txt.WriteString(menuItem.Text)
+		txt.WriteString(translate(menuItem.Text))
 		menuTexts[i] = txt
 	}
 
@@ -50,8 +58,8 @@ func (s *Scene) drawMainMenu(win *pixelgl.Window) {
 
 	regularFace := fileio.TtfFromBytesMust(goregular.TTF, 20)
 	boldFace := fileio.TtfFromBytesMust(gobold.TTF, 20)
-	atlasRegular := text.NewAtlas(regularFace, text.ASCII)
-	atlasBold := text.NewAtlas(boldFace, text.ASCII)
+	atlasRegular := text.NewAtlas(regularFace, text.ASCII, translationRunes)
+	atlasBold := text.NewAtlas(boldFace, text.ASCII, translationRunes)
 
 	menuTexts := returnMenuTexts(atlasRegular, atlasBold)
 	for i, menuText := range menuTexts {
@@ -59,11 +67,22 @@ func (s *Scene) drawMainMenu(win *pixelgl.Window) {
 		verticalAdjustVector := pixel.V(0, float64(-menuTextVerticalOffset*i))
 		menuText.Draw(win, centerTextMatrix.Moved(verticalAdjustVector))
 	}
+
+	if globalMainMenuMessage != `` {
+		messageText := text.New(pixel.ZV, atlasRegular)
+		messageText.Color = colornames.Gray
+		messageText.WriteString(globalMainMenuMessage)
+		centerTextMatrix := pixel.IM.Moved(win.Bounds().Center().Sub(messageText.Bounds().Center()))
+		messageText.Draw(win, centerTextMatrix.Moved(pixel.V(0, float64(-menuTextVerticalOffset*(len(menuTexts)+1)))))
+	}
 }
 
 //HandleMainMenuAndReturnState handles menu items and the associated states
 func (s *Scene) onUpdateMainMenu(win *pixelgl.Window) {
 
+	if win.JustPressed(pixelgl.KeyDown) || win.JustPressed(pixelgl.KeyUp) || win.JustPressed(pixelgl.KeyEnter) {
+		globalMainMenuMessage = ``
+	}
 	if win.JustPressed(pixelgl.KeyDown) {
 		for i, menuItem := range globalMenuItems {
 			if menuItem.State == "selected" && i < len(globalMenuItems)-1 {
@@ -85,7 +104,20 @@ func (s *Scene) onUpdateMainMenu(win *pixelgl.Window) {
 
 	if win.JustPressed(pixelgl.KeyEnter) {
 		for _, menuItem := range globalMenuItems {
-			if menuItem.State == "selected" {
+			if menuItem.State != "selected" {
+				continue
+			}
+			switch menuItem.sceneName {
+			case `Continue`:
+				if err := continueGame(); err == errNoGameToContinue {
+					globalMainMenuMessage = translate("There is no game to continue yet.")
+				} else if err != nil {
+					log.Println(err)
+					globalMainMenuMessage = translate("The saved game couldn't be loaded.")
+				}
+			case `Load`:
+				openLoadMenu()
+			default:
 				GlobalCurrentScene = menuItem.sceneName
 			}
 		}
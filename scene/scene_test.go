@@ -1,6 +1,7 @@
 package scene
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -10,7 +11,7 @@ func TestLoadFilesToSceneMap(t *testing.T) {
 		t.Fatalf("GlobalScenes is empty")
 	}
 	for sceneName, sceneObj := range GlobalScenes {
-		if sceneName == `Void` || sceneName == `Demo` || sceneName == `MainMenu` {
+		if sceneName == `Void` || isSpecialScene(sceneName) {
 			continue
 		}
 		if len(sceneObj.mapConfigPath) == 0 {
@@ -19,6 +20,9 @@ func TestLoadFilesToSceneMap(t *testing.T) {
 		if len(sceneObj.script.filePath) == 0 {
 			t.Fatalf("The scene '" + sceneName + "' has no filePath")
 		}
+		if sceneObj.script.parseErr != nil {
+			t.Fatalf("The script of scene '%s' couldn't be parsed:\n%v", sceneName, sceneObj.script.parseErr)
+		}
 
 		directions := sceneObj.mapConfig.Directions
 		_, directionsHasNorth := directions[`north`]
@@ -57,3 +61,376 @@ func TestRemoveMarkdownComments(t *testing.T) {
 		t.Fatal("Markdown comments have not been removed as planned!")
 	}
 }
+
+func TestKeywordResponseDeliversEveryParagraph(t *testing.T) {
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"You stand at the shore.\n"+
+		"\n"+
+		"`(Dive into the water) > swimming`\n"+
+		"\n"+
+		"The water is cold.\n"+
+		"\n"+
+		"You start to swim anyway.\n"+
+		"\n"+
+		"# swimming\n"+
+		"You swim.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	s.deliverNextResponse()
+
+	expectedTexts := []string{`The water is cold.`, `You start to swim anyway.`, `You swim.`}
+	s.handlePlayerCommand(`dive into the water`)
+	for idx, expectedText := range expectedTexts {
+		if idx > 0 && !s.deliverNextResponse() {
+			t.Fatalf("The response queue ran empty before %q", expectedText)
+		}
+		if globalNarrator.currentTextString != expectedText {
+			t.Fatalf("Expected narrator text %q but got %q", expectedText, globalNarrator.currentTextString)
+		}
+	}
+	if s.progress != `swimming` {
+		t.Fatalf("Expected progress 'swimming' but got '%s'", s.progress)
+	}
+}
+
+func TestConditionalKeywordsAndLines(t *testing.T) {
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"A door.\n"+
+		"\n"+
+		"`(Open door) ? has:doorKey & !flag:doorJammed > opened`\n"+
+		"\n"+
+		"`(Open door)`\n"+
+		"\n"+
+		"The door is locked.\n"+
+		"\n"+
+		"# opened\n"+
+		"`[If: flag:doorJammed]`\n"+
+		"\n"+
+		"This line is skipped.\n"+
+		"\n"+
+		"The door swings open.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	s.deliverNextResponse()
+
+	s.handlePlayerCommand(`open door`)
+	if globalNarrator.currentTextString != `The door is locked.` {
+		t.Fatalf("Expected the locked door without a key but got %q", globalNarrator.currentTextString)
+	}
+
+	globalPlayer.addItem(`doorKey`, &sceneObject{Name: `door key`})
+	defer func() { globalPlayer.items = nil }()
+	s.handlePlayerCommand(`Open door`)
+	if s.progress != `opened` || globalNarrator.currentTextString != `The door swings open.` {
+		t.Fatalf("Expected to open the door but got progress '%s' and text %q",
+			s.progress, globalNarrator.currentTextString)
+	}
+}
+
+func TestVariableDirectivesAndInterpolation(t *testing.T) {
+	defer func() { globalWorld.variables = make(map[string]string) }()
+
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"`[Set: torch_lit = true]`\n"+
+		"`[Add: coins 5]`\n"+
+		"`[Add: coins -2]`\n"+
+		"\n"+
+		"You have {coins} coins.\n"+
+		"\n"+
+		"`[If: flag:torch_lit & coins >= 3 & coins != 4]`\n"+
+		"\n"+
+		"The torch lights up {coins} coins.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expectedText := range []string{`You have 3 coins.`, `The torch lights up 3 coins.`} {
+		if !s.deliverNextResponse() || globalNarrator.currentTextString != expectedText {
+			t.Fatalf("Expected narrator text %q but got %q", expectedText, globalNarrator.currentTextString)
+		}
+	}
+}
+
+func TestKeywordVariants(t *testing.T) {
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"`(Inspect sand)`\n"+
+		"`[Variants: once]`\n"+
+		"\n"+
+		"You see sand.\n"+
+		"\n"+
+		"---\n"+
+		"\n"+
+		"Still sand.\n"+
+		"\n"+
+		"`(Listen)`\n"+
+		"\n"+
+		"Waves.\n"+
+		"\n"+
+		"---\n"+
+		"\n"+
+		"Gulls.\n"+
+		"\n"+
+		"`(Wait)`\n"+
+		"`[Variants: random]`\n"+
+		"\n"+
+		"Nothing happens.\n"+
+		"\n"+
+		"---\n"+
+		"\n"+
+		"Time passes.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	commandTexts := [][2]string{
+		{`inspect sand`, `You see sand.`}, {`inspect sand`, `Still sand.`}, {`inspect sand`, `Still sand.`},
+		{`listen`, `Waves.`}, {`listen`, `Gulls.`}, {`listen`, `Waves.`},
+	}
+	for _, commandText := range commandTexts {
+		s.handlePlayerCommand(commandText[0])
+		if globalNarrator.currentTextString != commandText[1] {
+			t.Fatalf("Expected %q for '%s' but got %q", commandText[1], commandText[0], globalNarrator.currentTextString)
+		}
+	}
+
+	var randomTexts [2][]string
+	for run := range randomTexts {
+		SeedRandom(42)
+		for i := 0; i < 8; i++ {
+			s.handlePlayerCommand(`wait`)
+			randomTexts[run] = append(randomTexts[run], globalNarrator.currentTextString)
+		}
+	}
+	if strings.Join(randomTexts[0], ` `) != strings.Join(randomTexts[1], ` `) {
+		t.Fatalf("The same seed led to different random variants: %v and %v", randomTexts[0], randomTexts[1])
+	}
+}
+
+func TestKeywordSynonymsAndFuzzyMatching(t *testing.T) {
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"`(Inspect reflection|examine glint)`\n"+
+		"\n"+
+		"It is a compass.\n"+
+		"\n"+
+		"`(Touch sand)`\n"+
+		"\n"+
+		"The sand is warm.\n"+
+		"\n"+
+		"`(Touch hand)`\n"+
+		"\n"+
+		"Your hand is cold.\n"+
+		"\n"+
+		"`(End)`\n"+
+		"\n"+
+		"The end.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	commandTexts := [][2]string{
+		{`inspect the reflection`, `It is a compass.`},
+		{`Examine glint`, `It is a compass.`},
+		{`Inspect reflecton`, `It is a compass.`},
+		{`touch the sand`, `The sand is warm.`},
+		{`touch land`, `Did you mean 'Touch hand' or 'Touch sand'?`},
+		// Short commands forgive one typo, longer ones two
+		{`ent`, `The end.`},
+		{`inzpect reflecton`, `It is a compass.`},
+	}
+	for _, commandText := range commandTexts {
+		globalNarrator.currentTextString = ``
+		s.handlePlayerCommand(commandText[0])
+		if globalNarrator.currentTextString != commandText[1] {
+			t.Fatalf("Expected %q for '%s' but got %q", commandText[1], commandText[0], globalNarrator.currentTextString)
+		}
+	}
+
+	// One typo more than forgiven isn't understood
+	for _, command := range []string{`eat`, `no`, `inzpekt reflecton`} {
+		globalNarrator.currentTextString = ``
+		s.handlePlayerCommand(command)
+		if text := globalNarrator.currentTextString; text == `The end.` || text == `It is a compass.` ||
+			strings.HasPrefix(text, `Did you mean`) {
+			t.Fatalf("Expected '%s' not to match a keyword but got %q", command, text)
+		}
+	}
+}
+
+func TestUnknownCommandFallbacks(t *testing.T) {
+	s := getSceneObjectWithDefaults()
+	s.mapConfig = &MapConfig{Fallback: []string{`The scene ignores '{input}'.`}}
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"`(Dance) > dancing`\n"+
+		"\n"+
+		"# dancing\n"+
+		"`(*)`\n"+
+		"\n"+
+		"You are too busy dancing to '{input}'.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.handlePlayerCommand(`sing <loudly>`)
+	if globalNarrator.currentTextString != `The scene ignores 'sing loudly'.` {
+		t.Fatalf("Expected the scene's fallback but got %q", globalNarrator.currentTextString)
+	}
+
+	s.mapConfig = nil
+	s.handlePlayerCommand(`sing`)
+	isGlobalFallback := false
+	for _, fallbackLine := range globalFallbackLines {
+		isGlobalFallback = isGlobalFallback || globalNarrator.currentTextString == strings.Replace(fallbackLine, `{input}`, `sing`, 1)
+	}
+	if !isGlobalFallback {
+		t.Fatalf("Expected a global fallback but got %q", globalNarrator.currentTextString)
+	}
+
+	s.handlePlayerCommand(`dance`)
+	s.handlePlayerCommand(`sing`)
+	if globalNarrator.currentTextString != `You are too busy dancing to 'sing'.` {
+		t.Fatalf("Expected the section's catch-all but got %q", globalNarrator.currentTextString)
+	}
+}
+
+func TestReenterSceneWithCatchAll(t *testing.T) {
+	previousScenes, previousCurrentScene := GlobalScenes, GlobalCurrentScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene = previousScenes, previousCurrentScene
+	}()
+
+	beach := getSceneObjectWithDefaults()
+	beach.Name = `Beach`
+	beach.mapConfig = &MapConfig{Directions: map[string]string{`north`: `Lighthouse`}}
+	beach.script.parsed, beach.script.parseErr = parseScript(`Beach/script.md`, "# beginning\n"+
+		"The waves roll in.\n"+
+		"\n"+
+		"`(*)`\n"+
+		"The waves drown out '{input}'.\n")
+	lighthouse := getSceneObjectWithDefaults()
+	lighthouse.Name = `Lighthouse`
+	lighthouse.mapConfig = &MapConfig{Directions: map[string]string{`south`: `Beach`}}
+	lighthouse.script.parsed, lighthouse.script.parseErr = parseScript(`Lighthouse/script.md`, "# beginning\n"+
+		"A lighthouse.\n")
+	if beach.script.parseErr != nil || lighthouse.script.parseErr != nil {
+		t.Fatal(beach.script.parseErr, lighthouse.script.parseErr)
+	}
+
+	GlobalScenes = map[string]*Scene{`Beach`: beach, `Lighthouse`: lighthouse}
+	GlobalCurrentScene = `Beach`
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	beach.deliverNextResponse()
+	if !beach.hasKeywords() {
+		t.Fatal("Expected the section's catch-all to be loaded")
+	}
+
+	beach.handlePlayerCommand(`go north`)
+	if GlobalCurrentScene != `Lighthouse` {
+		t.Fatalf("Expected to be at the lighthouse but got %q", GlobalCurrentScene)
+	}
+	if beach.hasKeywords() {
+		t.Fatal("The keywords of the scene which has been left should have been reset")
+	}
+
+	lighthouse.handlePlayerCommand(`go south`)
+	if GlobalCurrentScene != `Beach` {
+		t.Fatalf("Expected to be back at the beach but got %q", GlobalCurrentScene)
+	}
+	// Entering the scene again starts its section over like 'scene.OnUpdate' does
+	if len(beach.script.responseQueue) != 0 || beach.hasKeywords() {
+		t.Fatal("Expected the beach's section to be loaded again when it is entered")
+	}
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	beach.deliverNextResponse()
+	if globalNarrator.currentTextString != `The waves roll in.` {
+		t.Fatalf("Expected the section's text again but got %q", globalNarrator.currentTextString)
+	}
+}
+
+func TestCrossSceneJump(t *testing.T) {
+	previousScenes, previousCurrentScene := GlobalScenes, GlobalCurrentScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene = previousScenes, previousCurrentScene
+	}()
+
+	beach := getSceneObjectWithDefaults()
+	beach.Name = `Beach`
+	beach.script.parsed, beach.script.parseErr = parseScript(`Beach/script.md`, "# beginning\n"+
+		"You stand at the shore.\n"+
+		"\n"+
+		"`(Enter lighthouse) > Lighthouse#entrance`\n"+
+		"\n"+
+		"The door creaks.\n"+
+		"\n"+
+		"`(*)`\n"+
+		"The wind carries '{input}' away.\n")
+	lighthouse := getSceneObjectWithDefaults()
+	lighthouse.Name = `Lighthouse`
+	lighthouse.script.parsed, lighthouse.script.parseErr = parseScript(`Lighthouse/script.md`, "# beginning\n"+
+		"A lighthouse.\n"+
+		"\n"+
+		"# entrance\n"+
+		"It is dark inside.\n"+
+		"\n"+
+		"`(Climb stairs)`\n"+
+		"You climb.\n")
+	if beach.script.parseErr != nil || lighthouse.script.parseErr != nil {
+		t.Fatal(beach.script.parseErr, lighthouse.script.parseErr)
+	}
+	lighthouse.script.keywordResponseMap = map[string][]keywordAlternative{`stale keyword`: nil}
+
+	GlobalScenes = map[string]*Scene{`Beach`: beach, `Lighthouse`: lighthouse}
+	GlobalCurrentScene = `Beach`
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	beach.deliverNextResponse()
+
+	beach.handlePlayerCommand(`enter the lighthouse`)
+	if globalNarrator.currentTextString != `The door creaks.` {
+		t.Fatalf("Expected the keyword's answer before the jump but got %q", globalNarrator.currentTextString)
+	}
+	beach.deliverNextResponse()
+	if GlobalCurrentScene != `Lighthouse` || lighthouse.progress != `entrance` {
+		t.Fatalf("Expected to be at 'Lighthouse#entrance' but got '%s#%s'", GlobalCurrentScene, lighthouse.progress)
+	}
+	if lighthouse.hasKeywords() || beach.hasKeywords() {
+		t.Fatalf("The keyword maps of both scenes should have been reset")
+	}
+
+	if err := lighthouse.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	lighthouse.deliverNextResponse()
+	if globalNarrator.currentTextString != `It is dark inside.` {
+		t.Fatalf("Expected the destination section's text but got %q", globalNarrator.currentTextString)
+	}
+}
@@ -0,0 +1,409 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the schemas of the object and map config files and checks the files against them when they are
+// loaded. Problems name the file and the JSON path, e.g.
+//
+//	Beach/oldCupboard.json: $.conditions.keyCupboard1.action: must be a string but is a number
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// sceneObject is an object of a scene like 'oldCupboard.json', see 'object.go' for an example.
+type sceneObject struct {
+	// ID is how conditions refer to the object, e.g. 'keyCupboard1'
+	ID          string
+	Scene       string
+	Name        string
+	Description string
+	// Attributes contain the object's state (e.g. 'lock_state'), i.e. every key of the file which isn't part of the
+	// schema. Numbers and booleans are kept as they are written.
+	Attributes map[string]string
+	// Actions map a verb (e.g. 'open') to what happens when the player uses it on the object
+	Actions map[string]*objectAction
+	// Conditions map the ID of an item to what happens when it is used on the object
+	Conditions map[string]*objectCondition
+}
+
+// objectAction is either a text or texts depending on the object's attributes.
+type objectAction struct {
+	Text string
+	// ConditionalTexts map attribute conditions like 'lock_state=locked' to texts
+	ConditionalTexts map[string]string
+}
+
+// objectCondition is what happens when an item is used on the object.
+type objectCondition struct {
+	// Action is the verb, e.g. 'use'
+	Action string
+	// Target is the ID of the object the item has to be used on, the object itself if it's empty
+	Target          string
+	AttributeChange *attributeChange
+	Message         string
+}
+
+// attributeChange sets the attribute of an object which is kept for the rest of the game.
+type attributeChange struct {
+	// Target is the ID of the changed object, the object with the condition if it's empty
+	Target    string
+	Attribute string
+	Value     string
+}
+
+// copy returns a copy of the object whose attributes can be changed without changing the original ones. Actions and
+// conditions don't change while playing and are shared.
+func (o *sceneObject) copy() *sceneObject {
+	objectCopy := *o
+	objectCopy.Attributes = make(map[string]string)
+	for attribute, value := range o.Attributes {
+		objectCopy.Attributes[attribute] = value
+	}
+	return &objectCopy
+}
+
+// contentDecoder converts the JSON of a content file into its Go type and collects all problems of the file so writers
+// can fix them in one go.
+type contentDecoder struct {
+	filePath string
+	problems ScriptErrorList
+}
+
+// readJSON reads the file. Syntax errors (e.g. a trailing comma) are reported with their line.
+func (d *contentDecoder) readJSON() (interface{}, bool) {
+	jsonBytes, err := ioutil.ReadFile(d.filePath)
+	if err != nil {
+		d.problems = append(d.problems, &ScriptError{File: d.filePath, Msg: err.Error()})
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		problem := &ScriptError{File: d.filePath, Msg: err.Error()}
+		if syntaxError, isSyntaxError := err.(*json.SyntaxError); isSyntaxError {
+			problem.Line = bytes.Count(jsonBytes[:syntaxError.Offset], []byte("\n")) + 1
+		}
+		d.problems = append(d.problems, problem)
+		return nil, false
+	}
+	return value, true
+}
+
+func (d *contentDecoder) addProblem(path, format string, args ...interface{}) {
+	d.problems = append(d.problems, &ScriptError{File: d.filePath, Msg: path + ": " + fmt.Sprintf(format, args...)})
+}
+
+// getError returns the problems found or nil so that callers can check for 'err != nil'.
+func (d *contentDecoder) getError() error {
+	if len(d.problems) == 0 {
+		return nil
+	}
+	return d.problems
+}
+
+// getJSONTypeName describes the type of a JSON value for problems, e.g. 'a number'.
+func getJSONTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return `a string`
+	case json.Number:
+		return `a number`
+	case bool:
+		return `a boolean`
+	case []interface{}:
+		return `an array`
+	case map[string]interface{}:
+		return `an object`
+	}
+	return `null`
+}
+
+// getSortedKeys returns the keys of a JSON object in alphabetical order so problems are always reported the same way.
+func getSortedKeys(fields map[string]interface{}) []string {
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *contentDecoder) getObject(path string, value interface{}) (map[string]interface{}, bool) {
+	fields, isObject := value.(map[string]interface{})
+	if !isObject {
+		d.addProblem(path, "must be an object but is %s", getJSONTypeName(value))
+	}
+	return fields, isObject
+}
+
+func (d *contentDecoder) getString(path string, value interface{}) (string, bool) {
+	str, isString := value.(string)
+	if !isString {
+		d.addProblem(path, "must be a string but is %s", getJSONTypeName(value))
+	}
+	return str, isString
+}
+
+// setString sets str to the value if it is a string and returns whether it is.
+func (d *contentDecoder) setString(path string, value interface{}, str *string) bool {
+	valueString, isString := d.getString(path, value)
+	if isString {
+		*str = valueString
+	}
+	return isString
+}
+
+// getScalar returns strings, numbers and booleans as they are written in the file.
+func (d *contentDecoder) getScalar(path string, value interface{}) (string, bool) {
+	switch scalar := value.(type) {
+	case string:
+		return scalar, true
+	case json.Number:
+		return scalar.String(), true
+	case bool:
+		return fmt.Sprint(scalar), true
+	}
+	d.addProblem(path, "must be a string, a number or a boolean but is %s", getJSONTypeName(value))
+	return ``, false
+}
+
+// hasRequiredKeys returns whether the object has all keys it must have.
+func (d *contentDecoder) hasRequiredKeys(path string, fields map[string]interface{}, keys ...string) bool {
+	hasKeys := true
+	for _, key := range keys {
+		if _, isFound := fields[key]; !isFound {
+			d.addProblem(path, "'%s' is missing", key)
+			hasKeys = false
+		}
+	}
+	return hasKeys
+}
+
+// loadObjectFile reads an object file into object. The keys of the file replace the ones the object already has so
+// that a localized file only needs the translated texts.
+func loadObjectFile(filePath string, object *sceneObject) error {
+	d := &contentDecoder{filePath: filePath}
+	value, isRead := d.readJSON()
+	if !isRead {
+		return d.getError()
+	}
+	fields, isObject := d.getObject(`$`, value)
+	if !isObject {
+		return d.getError()
+	}
+
+	if object.Attributes == nil {
+		object.Attributes = make(map[string]string)
+	}
+	for _, key := range getSortedKeys(fields) {
+		path := `$.` + key
+		switch key {
+		case `id`:
+			d.setString(path, fields[key], &object.ID)
+		case `scene`:
+			d.setString(path, fields[key], &object.Scene)
+		case `name`:
+			d.setString(path, fields[key], &object.Name)
+		case `description`:
+			d.setString(path, fields[key], &object.Description)
+		case `actions`:
+			d.decodeActions(path, fields[key], object)
+		case `conditions`:
+			d.decodeConditions(path, fields[key], object)
+		default:
+			if attributeValue, isScalar := d.getScalar(path, fields[key]); isScalar {
+				object.Attributes[key] = attributeValue
+			}
+		}
+	}
+
+	// Attribute conditions are checked after loading so that localized texts may refer to the default attributes
+	for _, verb := range getSortedActionVerbs(object.Actions) {
+		for attributeCondition := range object.Actions[verb].ConditionalTexts {
+			attribute, _ := splitAttributeCondition(attributeCondition)
+			if _, isAttribute := object.Attributes[attribute]; !isAttribute {
+				d.addProblem(`$.actions.`+verb+`.`+attributeCondition, "the object has no attribute '%s'", attribute)
+			}
+		}
+	}
+	return d.getError()
+}
+
+func getSortedActionVerbs(actions map[string]*objectAction) []string {
+	var verbs []string
+	for verb := range actions {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	return verbs
+}
+
+// decodeActions reads '"actions": {"look": "text", "open": {"lock_state=locked": "text"}}'.
+func (d *contentDecoder) decodeActions(path string, value interface{}, object *sceneObject) {
+	fields, isObject := d.getObject(path, value)
+	if !isObject {
+		return
+	}
+	if object.Actions == nil {
+		object.Actions = make(map[string]*objectAction)
+	}
+
+	for _, verb := range getSortedKeys(fields) {
+		actionPath := path + `.` + verb
+		if text, isString := fields[verb].(string); isString {
+			object.Actions[verb] = &objectAction{Text: text}
+			continue
+		}
+		conditionalTexts, isObject := fields[verb].(map[string]interface{})
+		if !isObject {
+			d.addProblem(actionPath, "must be a string or an object but is %s", getJSONTypeName(fields[verb]))
+			continue
+		}
+
+		action := &objectAction{ConditionalTexts: make(map[string]string)}
+		for _, attributeCondition := range getSortedKeys(conditionalTexts) {
+			textPath := actionPath + `.` + attributeCondition
+			if attribute, _ := splitAttributeCondition(attributeCondition); attribute == `` ||
+				!strings.Contains(attributeCondition, `=`) {
+				d.addProblem(textPath, "must be written like 'lock_state=locked'")
+				continue
+			}
+			if text, isString := d.getString(textPath, conditionalTexts[attributeCondition]); isString {
+				action.ConditionalTexts[attributeCondition] = text
+			}
+		}
+		object.Actions[verb] = action
+	}
+}
+
+// decodeConditions reads '"conditions": {"keyCupboard1": {"action": "use", ...}}'.
+func (d *contentDecoder) decodeConditions(path string, value interface{}, object *sceneObject) {
+	fields, isObject := d.getObject(path, value)
+	if !isObject {
+		return
+	}
+	if object.Conditions == nil {
+		object.Conditions = make(map[string]*objectCondition)
+	}
+
+	for _, itemID := range getSortedKeys(fields) {
+		conditionPath := path + `.` + itemID
+		conditionFields, isObject := d.getObject(conditionPath, fields[itemID])
+		if !isObject {
+			continue
+		}
+
+		condition := &objectCondition{}
+		isValid := d.hasRequiredKeys(conditionPath, conditionFields, `action`)
+		for _, key := range getSortedKeys(conditionFields) {
+			keyPath := conditionPath + `.` + key
+			isKeyValid := true
+			switch key {
+			case `action`:
+				isKeyValid = d.setString(keyPath, conditionFields[key], &condition.Action)
+			case `target`:
+				isKeyValid = d.setString(keyPath, conditionFields[key], &condition.Target)
+			case `message`:
+				isKeyValid = d.setString(keyPath, conditionFields[key], &condition.Message)
+			case `attribute_change`:
+				condition.AttributeChange = d.decodeAttributeChange(keyPath, conditionFields[key])
+				isKeyValid = condition.AttributeChange != nil
+			default:
+				d.addProblem(keyPath, "is unknown, a condition has an 'action', 'target', 'attribute_change' and "+
+					"'message'")
+				isKeyValid = false
+			}
+			isValid = isValid && isKeyValid
+		}
+		if isValid {
+			object.Conditions[itemID] = condition
+		}
+	}
+}
+
+// decodeAttributeChange reads '"attribute_change": {"target": "oldCupboard1", "attribute": "lock_state", ...}'.
+func (d *contentDecoder) decodeAttributeChange(path string, value interface{}) *attributeChange {
+	fields, isObject := d.getObject(path, value)
+	if !isObject {
+		return nil
+	}
+
+	change := &attributeChange{}
+	isValid := d.hasRequiredKeys(path, fields, `attribute`, `value`)
+	for _, key := range getSortedKeys(fields) {
+		keyPath := path + `.` + key
+		isKeyValid := true
+		switch key {
+		case `target`:
+			isKeyValid = d.setString(keyPath, fields[key], &change.Target)
+		case `attribute`:
+			isKeyValid = d.setString(keyPath, fields[key], &change.Attribute)
+		case `value`:
+			change.Value, isKeyValid = d.getScalar(keyPath, fields[key])
+		default:
+			d.addProblem(keyPath, "is unknown, an attribute change has a 'target', 'attribute' and 'value'")
+			isKeyValid = false
+		}
+		isValid = isValid && isKeyValid
+	}
+	if !isValid {
+		return nil
+	}
+	return change
+}
+
+// loadMapConfigFile reads a map config file into mapConfig. The keys of the file replace the ones mapConfig already
+// has so that a localized map config only needs the translated entries.
+func loadMapConfigFile(filePath string, mapConfig *MapConfig) error {
+	d := &contentDecoder{filePath: filePath}
+	value, isRead := d.readJSON()
+	if !isRead {
+		return d.getError()
+	}
+	fields, isObject := d.getObject(`$`, value)
+	if !isObject {
+		return d.getError()
+	}
+
+	for _, key := range getSortedKeys(fields) {
+		path := `$.` + key
+		switch key {
+		case `directions`:
+			directions, isObject := d.getObject(path, fields[key])
+			if !isObject {
+				continue
+			}
+			mapConfig.Directions = make(map[string]string)
+			for _, direction := range getSortedKeys(directions) {
+				if sceneName, isString := d.getString(path+`.`+direction, directions[direction]); isString {
+					mapConfig.Directions[direction] = sceneName
+				}
+			}
+		case `look`:
+			d.setString(path, fields[key], &mapConfig.Look)
+		case `fallback`:
+			fallbackLines, isArray := fields[key].([]interface{})
+			if !isArray {
+				d.addProblem(path, "must be an array but is %s", getJSONTypeName(fields[key]))
+				continue
+			}
+			mapConfig.Fallback = nil
+			for idx, fallbackLine := range fallbackLines {
+				if line, isString := d.getString(fmt.Sprintf("%s[%d]", path, idx), fallbackLine); isString {
+					mapConfig.Fallback = append(mapConfig.Fallback, line)
+				}
+			}
+		default:
+			d.addProblem(path, "is unknown, a map config has 'directions', 'look' and 'fallback'")
+		}
+	}
+	return d.getError()
+}
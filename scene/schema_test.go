@@ -0,0 +1,98 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadContentFileProblems(t *testing.T) {
+	contentDir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+
+	tests := []struct {
+		fileName         string
+		content          string
+		expectedProblems []string
+	}{
+		{`mapConfig.json`, "{\n    \"look\": \"Sand.\",\n}", []string{
+			`mapConfig.json:3: invalid character '}' looking for beginning of object key string`,
+		}},
+		{`mapConfig.json`, `{"directions": {"north": 1}, "fallback": "Nothing.", "visited": 2}`, []string{
+			`mapConfig.json: $.directions.north: must be a string but is a number`,
+			`mapConfig.json: $.fallback: must be an array but is a string`,
+			`mapConfig.json: $.visited: is unknown, a map config has 'directions', 'look' and 'fallback'`,
+		}},
+		{`oldCupboard.json`, `{
+			"id": "oldCupboard1",
+			"lock_state": "locked",
+			"hinges": ["rusty"],
+			"actions": {
+				"open": {"locked": "The door won't budge.", "lid_state=open": "It is open."},
+				"kick": 3
+			},
+			"conditions": {
+				"keyCupboard1": {
+					"target": "oldCupboard1",
+					"attribute_change": {"attribute": "lock_state", "value": null},
+					"sound": "click"
+				}
+			}
+		}`, []string{
+			`oldCupboard.json: $.actions.kick: must be a string or an object but is a number`,
+			`oldCupboard.json: $.actions.open.locked: must be written like 'lock_state=locked'`,
+			`oldCupboard.json: $.conditions.keyCupboard1: 'action' is missing`,
+			`oldCupboard.json: $.conditions.keyCupboard1.attribute_change.value: must be a string, a number or a ` +
+				`boolean but is null`,
+			`oldCupboard.json: $.conditions.keyCupboard1.sound: is unknown, a condition has an 'action', 'target', ` +
+				`'attribute_change' and 'message'`,
+			`oldCupboard.json: $.hinges: must be a string, a number or a boolean but is an array`,
+			`oldCupboard.json: $.actions.open.lid_state=open: the object has no attribute 'lid_state'`,
+		}},
+	}
+	for _, test := range tests {
+		filePath := contentDir + test.fileName
+		if err := ioutil.WriteFile(filePath, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if test.fileName == `mapConfig.json` {
+			err = loadMapConfigFile(filePath, &MapConfig{})
+		} else {
+			err = loadObjectFile(filePath, &sceneObject{})
+		}
+
+		var problems []string
+		if err != nil {
+			problems = strings.Split(strings.ReplaceAll(err.Error(), contentDir, ``), "\n")
+		}
+		if strings.Join(problems, "\n") != strings.Join(test.expectedProblems, "\n") {
+			t.Errorf("Expected the problems\n%s\nbut got\n%s", strings.Join(test.expectedProblems, "\n"),
+				strings.Join(problems, "\n"))
+		}
+	}
+
+	// The invalid entries are skipped, the valid ones are loaded
+	object := &sceneObject{}
+	loadObjectFile(contentDir+`oldCupboard.json`, object)
+	if object.ID != `oldCupboard1` || object.Attributes[`lock_state`] != `locked` ||
+		object.Conditions[`keyCupboard1`] != nil {
+		t.Errorf("Expected the id and the attribute to be loaded without the invalid condition but got %+v", object)
+	}
+
+	// A scene whose map config can't be read is played without directions instead of crashing later
+	os.Mkdir(contentDir+`Island`, 0755)
+	ioutil.WriteFile(contentDir+`Island/mapConfig.json`, []byte(`{"look": "Sand.",}`), 0644)
+	island := getSceneObjectWithDefaults()
+	island.Name = `Island`
+	if _, isErrorList := island.loadSceneFiles(contentDir).(ScriptErrorList); !isErrorList {
+		t.Errorf("Expected the problems of the map config to be returned")
+	}
+	if island.mapConfig == nil || len(island.mapConfig.Directions) != 0 {
+		t.Errorf("Expected an empty map config but got %v", island.mapConfig)
+	}
+}
@@ -0,0 +1,571 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file turns a script.md into a tree of sections, narrator lines, ambience directives and keyword blocks.
+package scene
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ScriptError points at the line in a content file which couldn't be understood.
+//
+// Line is 0 if the problem concerns the whole file.
+type ScriptError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ScriptError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// ScriptErrorList collects all errors of a script file so writers can fix them in one go.
+type ScriptErrorList []*ScriptError
+
+func (l ScriptErrorList) Error() string {
+	var messages []string
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// scriptPosition marks where an element of the script has been defined.
+type scriptPosition struct {
+	file string
+	line int
+}
+
+func (p scriptPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+// scriptFile is the parsed representation of a script.md
+type scriptFile struct {
+	filePath string
+	sections []*scriptSection
+	// sectionMap maps a section name (the text behind '# ') to the section
+	sectionMap map[string]*scriptSection
+}
+
+// scriptSection contains everything below a '# name' heading.
+//
+// The narrator lines are delivered when the section becomes active, the keyword blocks afterwards wait for the player.
+type scriptSection struct {
+	name     string
+	pos      scriptPosition
+	lines    []*narratorLine
+	keywords []*keywordBlock
+	// idleLines are written below an `[Idle: ...]` directive anywhere in the section
+	idleLines []*narratorLine
+	// isWordMode is set with `[WordMode: on]`, the player can only use collected words then (see 'words.go')
+	isWordMode bool
+}
+
+// narratorLine is a paragraph of narrator text together with the ambience directives written above it.
+//
+// The text can be empty if ambience directives are the last thing in a section or keyword block.
+// An `[If: condition]` directive above the line skips it (including its ambience) unless the condition is met.
+type narratorLine struct {
+	text      string
+	ambience  []*ambienceDirective
+	condition *scriptCondition
+	pos       scriptPosition
+}
+
+// ambienceDirective is written as `[Kind: argument]`, e.g. `[Audio: Wave.ogg]`.
+type ambienceDirective struct {
+	kind     string
+	argument string
+	pos      scriptPosition
+}
+
+// keywordBlock is written as `(keyword)` or `(keyword) > target` and contains the narrator lines answering it.
+//
+// Synonyms are separated by '|', e.g. `(Inspect reflection|examine glint)`, keyword is the first one.
+//
+// The target is a section of the same script or, written as `Scene#section`, a section of another scene's script, e.g.
+// `(Enter lighthouse) > Lighthouse#entrance`.
+//
+// With `(keyword) ? condition` or `(keyword) ? condition > target` the keyword is only understood if the condition is
+// met. The same keyword can appear several times with different conditions.
+//
+// Alternative answers are separated by a '---' line. Which one is delivered is chosen by the variant mode set with
+// `[Variants: mode]`:
+// - cycle (default) delivers them in order and starts over after the last one
+// - once delivers them in order and then keeps repeating the last one as the default answer
+// - random picks one at random
+type keywordBlock struct {
+	keyword        string
+	synonyms       []string
+	condition      *scriptCondition
+	progressTarget string
+	variants       [][]*narratorLine
+	variantMode    string
+	pos            scriptPosition
+}
+
+// allLines returns the narrator lines of all variants.
+func (k *keywordBlock) allLines() []*narratorLine {
+	var lines []*narratorLine
+	for _, variant := range k.variants {
+		lines = append(lines, variant...)
+	}
+	return lines
+}
+
+const (
+	variantModeCycle  = `cycle`
+	variantModeOnce   = `once`
+	variantModeRandom = `random`
+)
+
+// ambienceArgumentRegexps contains the known ambience directive kinds and what their argument has to look like.
+var ambienceArgumentRegexps = map[string]*regexp.Regexp{
+	// Don't allow whitespace chars in filenames
+	`Audio`: regexp.MustCompile(`^\S+$`),
+	// e.g. `[Set: torch_lit = true]`
+	`Set`: setArgumentRegexp,
+	// e.g. `[Add: coins 5]` or `[Add: coins -1]`
+	`Add`: addArgumentRegexp,
+	// e.g. `[Give: cupboardKey]` with the name of an object file
+	giveDirective: regexp.MustCompile(`^\w+$`),
+	// e.g. `[Wait: 2s]`, `[AutoAdvance: 500ms]` or `[Idle: 1m]`, see 'advanceTimedScript'
+	waitDirective:        durationArgumentRegexp,
+	autoAdvanceDirective: durationArgumentRegexp,
+	idleDirective:        durationArgumentRegexp,
+}
+
+var (
+	sectionHeadingRegexp = regexp.MustCompile(`^# (.+?)\s*$`)
+	ambienceCmdRegexp    = regexp.MustCompile("^`\\[(\\w+):\\s?(.*?)\\]`$")
+	playerCmdRegexp      = regexp.MustCompile("^`\\((.+)\\)(?: \\? (.+?))?(?: > (.+))?`$")
+	commentRegexp        = regexp.MustCompile(`(?s)<!--.*?-->`)
+	variantSeparator     = regexp.MustCompile(`^-{3,}$`)
+	// e.g. `[Include: Common/ending.md#credits]`, the section is optional
+	includeArgumentRegexp = regexp.MustCompile(`^([\w./-]+\.md)(?:#(.+))?$`)
+)
+
+// scriptBlock is a paragraph of consecutive non-blank lines.
+type scriptBlock struct {
+	line  int
+	lines []string
+}
+
+// scriptParser holds the state while walking through the blocks of a script file.
+type scriptParser struct {
+	file   *scriptFile
+	errors ScriptErrorList
+
+	// filePath is the file the current block comes from, it differs from file.filePath inside of included text
+	filePath string
+	// contentDir is the directory include paths are relative to
+	contentDir string
+	// includeStack contains the includes (e.g. 'Common/ending.md#credits') currently being parsed
+	includeStack []string
+
+	currentSection *scriptSection
+	currentKeyword *keywordBlock
+	// pendingAmbience collects ambience directives until the next narrator line is found
+	pendingAmbience []*ambienceDirective
+	// pendingCondition guards the next narrator line
+	pendingCondition *scriptCondition
+	// pendingConditionLine is where the pending condition has been written
+	pendingConditionLine int
+}
+
+// parseScript parses the contents of a script file. The file path is only used for positions and error messages.
+//
+// The returned scriptFile contains everything that could be understood even if an error is returned.
+//
+// Scripts live in a scene folder so `[Include: ...]` paths are relative to the folder above, the content directory.
+func parseScript(filePath, content string) (*scriptFile, error) {
+	p := &scriptParser{
+		file: &scriptFile{
+			filePath:   filePath,
+			sectionMap: make(map[string]*scriptSection),
+		},
+		filePath:   filePath,
+		contentDir: filepath.Dir(filepath.Dir(filePath)) + "/",
+	}
+
+	for _, block := range splitScriptBlocks(content) {
+		p.parseBlock(block)
+	}
+	p.closeSection()
+
+	if len(p.errors) > 0 {
+		return p.file, p.errors
+	}
+	return p.file, nil
+}
+
+// splitScriptBlocks separates the content by blank lines and headings while keeping track of line numbers.
+//
+// Blank lines inside of markdown comments don't end a block.
+func splitScriptBlocks(content string) []scriptBlock {
+	var blocks []scriptBlock
+	var current *scriptBlock
+	isInComment := false
+
+	for idx, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		lineNumber := idx + 1
+
+		if !isInComment && (strings.TrimSpace(line) == `` || sectionHeadingRegexp.MatchString(line)) {
+			if current != nil {
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			if strings.TrimSpace(line) == `` {
+				continue
+			}
+			blocks = append(blocks, scriptBlock{line: lineNumber, lines: []string{line}})
+			continue
+		}
+
+		if current == nil {
+			current = &scriptBlock{line: lineNumber}
+		}
+		current.lines = append(current.lines, line)
+
+		// Only the last comment marker on a line decides whether a comment continues on the next line
+		lastOpen := strings.LastIndex(line, `<!--`)
+		lastClose := strings.LastIndex(line, `-->`)
+		if lastOpen > lastClose {
+			isInComment = true
+		} else if lastClose > lastOpen {
+			isInComment = false
+		}
+	}
+	if current != nil {
+		blocks = append(blocks, *current)
+	}
+
+	return blocks
+}
+
+func (p *scriptParser) addError(line int, format string, args ...interface{}) {
+	p.errors = append(p.errors, &ScriptError{
+		File: p.filePath,
+		Line: line,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *scriptParser) position(line int) scriptPosition {
+	return scriptPosition{file: p.filePath, line: line}
+}
+
+func (p *scriptParser) parseBlock(block scriptBlock) {
+	if len(block.lines) == 1 {
+		if headingMatch := sectionHeadingRegexp.FindStringSubmatch(block.lines[0]); headingMatch != nil {
+			p.openSection(headingMatch[1], block.line)
+			return
+		}
+	}
+
+	text := strings.TrimSpace(commentRegexp.ReplaceAllString(strings.Join(block.lines, "\n"), ``))
+	if text == `` {
+		return
+	}
+	if p.currentSection == nil {
+		p.addError(block.line, "text outside of a section (start the script with a heading like '# beginning')")
+		return
+	}
+
+	if variantSeparator.MatchString(text) {
+		p.startVariant(block.line)
+		return
+	}
+
+	// Directives have to stand on their own line but may be written without blank lines in between
+	if strings.HasPrefix(text, "`") {
+		for len(block.lines) > 0 {
+			line := strings.TrimSpace(commentRegexp.ReplaceAllString(block.lines[0], ``))
+			if line != `` && !strings.HasPrefix(line, "`") {
+				break
+			}
+			if line != `` {
+				p.parseDirective(line, block.line)
+			}
+			block.lines = block.lines[1:]
+			block.line++
+		}
+		// The remaining lines are narrator text directly below the directives
+		text = strings.TrimSpace(commentRegexp.ReplaceAllString(strings.Join(block.lines, "\n"), ``))
+		if text == `` {
+			return
+		}
+	}
+
+	p.addNarratorLine(&narratorLine{
+		text:      text,
+		ambience:  p.pendingAmbience,
+		condition: p.pendingCondition,
+		pos:       p.position(block.line),
+	})
+	p.pendingAmbience = nil
+	p.pendingCondition = nil
+}
+
+func (p *scriptParser) parseDirective(line string, lineNumber int) {
+	if ambienceMatch := ambienceCmdRegexp.FindStringSubmatch(line); ambienceMatch != nil {
+		kind, argument := ambienceMatch[1], strings.TrimSpace(ambienceMatch[2])
+		if kind == `If` {
+			p.parseLineCondition(argument, lineNumber)
+			return
+		}
+		if kind == `Variants` {
+			p.setVariantMode(argument, lineNumber)
+			return
+		}
+		if kind == `Include` {
+			p.include(argument, lineNumber)
+			return
+		}
+		if kind == wordModeDirective {
+			p.setWordMode(argument, lineNumber)
+			return
+		}
+		argumentRegexp, isKnown := ambienceArgumentRegexps[kind]
+		if !isKnown {
+			p.addError(lineNumber, "unknown ambience directive '%s'", kind)
+			return
+		}
+		if !argumentRegexp.MatchString(argument) {
+			p.addError(lineNumber, "invalid argument '%s' for ambience directive '%s'", argument, kind)
+			return
+		}
+		p.pendingAmbience = append(p.pendingAmbience, &ambienceDirective{
+			kind:     kind,
+			argument: argument,
+			pos:      p.position(lineNumber),
+		})
+		return
+	}
+
+	if playerCmdMatch := playerCmdRegexp.FindStringSubmatch(line); playerCmdMatch != nil {
+		p.flushPendingAmbience()
+		var synonyms []string
+		for _, synonym := range strings.Split(playerCmdMatch[1], `|`) {
+			if synonym = strings.TrimSpace(synonym); synonym == `` {
+				p.addError(lineNumber, "empty synonym in player command %s", line)
+				continue
+			}
+			synonyms = append(synonyms, synonym)
+		}
+		if len(synonyms) == 0 {
+			return
+		}
+		p.currentKeyword = &keywordBlock{
+			keyword:        synonyms[0],
+			synonyms:       synonyms,
+			progressTarget: strings.TrimSpace(playerCmdMatch[3]),
+			variants:       [][]*narratorLine{nil},
+			variantMode:    variantModeCycle,
+			pos:            p.position(lineNumber),
+		}
+		if playerCmdMatch[2] != `` {
+			condition, err := parseCondition(playerCmdMatch[2])
+			if err != nil {
+				p.addError(lineNumber, "%v", err)
+			}
+			p.currentKeyword.condition = condition
+		}
+		p.currentSection.keywords = append(p.currentSection.keywords, p.currentKeyword)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(line, "`["):
+		p.addError(lineNumber, "malformed ambience directive %s (expected `[Kind: argument]`)", line)
+	case strings.HasPrefix(line, "`("):
+		p.addError(lineNumber, "malformed player command %s (expected `(keyword)` or `(keyword) > target`)", line)
+	default:
+		p.addError(lineNumber, "unrecognized directive %s", line)
+	}
+}
+
+// addNarratorLine appends the line to the current keyword block or, before the first keyword, to the section itself.
+func (p *scriptParser) addNarratorLine(line *narratorLine) {
+	if getDirectiveDuration(line.ambience, idleDirective) > 0 {
+		p.currentSection.idleLines = append(p.currentSection.idleLines, line)
+	} else if p.currentKeyword != nil {
+		lastVariant := len(p.currentKeyword.variants) - 1
+		p.currentKeyword.variants[lastVariant] = append(p.currentKeyword.variants[lastVariant], line)
+	} else {
+		p.currentSection.lines = append(p.currentSection.lines, line)
+	}
+}
+
+// startVariant begins the next alternative answer of the current keyword.
+func (p *scriptParser) startVariant(lineNumber int) {
+	if p.currentKeyword == nil {
+		p.addError(lineNumber, "'---' separates answers of a keyword but there is no keyword before it")
+		return
+	}
+	p.flushPendingAmbience()
+	p.currentKeyword.variants = append(p.currentKeyword.variants, nil)
+}
+
+func (p *scriptParser) setVariantMode(mode string, lineNumber int) {
+	if p.currentKeyword == nil {
+		p.addError(lineNumber, "`[Variants: %s]` has to be written below a keyword", mode)
+		return
+	}
+	switch mode {
+	case variantModeCycle, variantModeOnce, variantModeRandom:
+		p.currentKeyword.variantMode = mode
+	default:
+		p.addError(lineNumber, "unknown variant mode '%s' (known are 'cycle', 'once' and 'random')", mode)
+	}
+}
+
+func (p *scriptParser) parseLineCondition(source string, lineNumber int) {
+	if p.pendingCondition != nil {
+		p.addError(lineNumber, "there is already an `[If: %s]` for the next narrator line (combine conditions with '&')",
+			p.pendingCondition.source)
+		return
+	}
+	condition, err := parseCondition(source)
+	if err != nil {
+		p.addError(lineNumber, "%v", err)
+		return
+	}
+	p.pendingCondition = condition
+	p.pendingConditionLine = lineNumber
+}
+
+// flushPendingAmbience keeps ambience directives that aren't followed by any narrator text.
+func (p *scriptParser) flushPendingAmbience() {
+	if len(p.pendingAmbience) == 0 {
+		if p.pendingCondition != nil {
+			p.addError(p.pendingConditionLine, "`[If: %s]` isn't followed by a narrator line",
+				p.pendingCondition.source)
+			p.pendingCondition = nil
+		}
+		return
+	}
+	p.addNarratorLine(&narratorLine{
+		ambience:  p.pendingAmbience,
+		condition: p.pendingCondition,
+		pos:       p.pendingAmbience[0].pos,
+	})
+	p.pendingAmbience = nil
+	p.pendingCondition = nil
+}
+
+// include parses the blocks of another file (or one section of it) as if they were written in place of the directive.
+//
+// Positions and errors inside of the included text point into the included file.
+func (p *scriptParser) include(target string, lineNumber int) {
+	includeMatch := includeArgumentRegexp.FindStringSubmatch(target)
+	if includeMatch == nil {
+		p.addError(lineNumber, "invalid include '%s' (expected e.g. `[Include: Common/ending.md#section]`)", target)
+		return
+	}
+	includePath, sectionName := filepath.ToSlash(filepath.Clean(includeMatch[1])), includeMatch[2]
+	if includePath == `..` || strings.HasPrefix(includePath, `../`) || filepath.IsAbs(includePath) {
+		p.addError(lineNumber, "included file '%s' isn't inside the content directory", includeMatch[1])
+		return
+	}
+	if target = includePath; sectionName != `` {
+		target += `#` + sectionName
+	}
+
+	for _, includedTarget := range p.includeStack {
+		if includedTarget == target {
+			p.addError(lineNumber, "circular include %s", strings.Join(append(p.includeStack, target), ` -> `))
+			return
+		}
+	}
+
+	content, err := ioutil.ReadFile(p.contentDir + includePath)
+	if err != nil {
+		p.addError(lineNumber, "included file '%s' can't be read: %v", includePath, err)
+		return
+	}
+
+	blocks := splitScriptBlocks(string(content))
+	if sectionName != `` {
+		sectionBlocks, isFound := selectSectionBlocks(blocks, sectionName)
+		if !isFound {
+			p.addError(lineNumber, "there is no section '# %s' in '%s'", sectionName, includePath)
+			return
+		}
+		blocks = sectionBlocks
+	} else {
+		for _, block := range blocks {
+			if isHeadingBlock(block) {
+				p.addError(lineNumber, "'%s' contains sections, include one of them with '%s#section'",
+					includePath, includePath)
+				return
+			}
+		}
+	}
+
+	includingFilePath := p.filePath
+	p.filePath = p.contentDir + includePath
+	p.includeStack = append(p.includeStack, target)
+	for _, block := range blocks {
+		p.parseBlock(block)
+	}
+	p.includeStack = p.includeStack[:len(p.includeStack)-1]
+	p.filePath = includingFilePath
+}
+
+func isHeadingBlock(block scriptBlock) bool {
+	return len(block.lines) == 1 && sectionHeadingRegexp.MatchString(block.lines[0])
+}
+
+// selectSectionBlocks returns the blocks below the heading of the given section up to the next heading.
+func selectSectionBlocks(blocks []scriptBlock, sectionName string) ([]scriptBlock, bool) {
+	for idx, block := range blocks {
+		if !isHeadingBlock(block) || sectionHeadingRegexp.FindStringSubmatch(block.lines[0])[1] != sectionName {
+			continue
+		}
+		end := idx + 1
+		for end < len(blocks) && !isHeadingBlock(blocks[end]) {
+			end++
+		}
+		return blocks[idx+1 : end], true
+	}
+	return nil, false
+}
+
+func (p *scriptParser) openSection(name string, lineNumber int) {
+	p.closeSection()
+
+	if previous, isDuplicate := p.file.sectionMap[name]; isDuplicate {
+		p.addError(lineNumber, "section '# %s' has already been defined at line %d", name, previous.pos.line)
+	}
+
+	p.currentSection = &scriptSection{
+		name: name,
+		pos:  p.position(lineNumber),
+	}
+	p.file.sections = append(p.file.sections, p.currentSection)
+	p.file.sectionMap[name] = p.currentSection
+}
+
+func (p *scriptParser) closeSection() {
+	if p.currentSection == nil {
+		return
+	}
+	p.flushPendingAmbience()
+	if len(p.currentSection.lines) == 0 && len(p.currentSection.keywords) == 0 && len(p.currentSection.idleLines) == 0 {
+		p.addError(p.currentSection.pos.line, "section '# %s' is empty", p.currentSection.name)
+	}
+	p.currentSection = nil
+	p.currentKeyword = nil
+}
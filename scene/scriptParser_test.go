@@ -0,0 +1,182 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := "# beginning\n" +
+		"`[Audio: Wave.ogg]`\n" +
+		"\n" +
+		"You open your eyes.<!-- A comment\n" +
+		"\n" +
+		"spanning a blank line. -->\n" +
+		"\n" +
+		"`(Inspect reflection) > get_compass`\n" +
+		"\n" +
+		"# get_compass\r\n" +
+		"A compass.\r\n" +
+		"\r\n" +
+		"`(Go south)`\r\n" +
+		"`[Audio: Wave.ogg]`\r\n" +
+		"South is nothing but the sea.\r\n"
+
+	parsed, err := parseScript(`test.md`, script)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	if len(parsed.sections) != 2 {
+		t.Fatalf("Expected 2 sections but got %d", len(parsed.sections))
+	}
+
+	beginning := parsed.sectionMap[`beginning`]
+	if len(beginning.lines) != 1 || beginning.lines[0].text != `You open your eyes.` {
+		t.Fatalf("Unexpected narrator lines in 'beginning': %+v", beginning.lines)
+	}
+	if len(beginning.lines[0].ambience) != 1 || beginning.lines[0].ambience[0].argument != `Wave.ogg` {
+		t.Fatalf("The ambience directive hasn't been attached to the first narrator line")
+	}
+	if beginning.keywords[0].keyword != `Inspect reflection` || beginning.keywords[0].progressTarget != `get_compass` {
+		t.Fatalf("Unexpected keyword block: %+v", beginning.keywords[0])
+	}
+
+	goSouth := parsed.sectionMap[`get_compass`].keywords[0]
+	if goSouth.pos.line != 13 {
+		t.Fatalf("Expected '(Go south)' at line 13 but got %d", goSouth.pos.line)
+	}
+	if len(goSouth.variants[0]) != 1 || goSouth.variants[0][0].text != `South is nothing but the sea.` {
+		t.Fatalf("Text directly below directives hasn't been parsed: %+v", goSouth.variants[0])
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	script := "Some text before any section.\n" +
+		"\n" +
+		"# beginning\n" +
+		"`[Smell: roses]`\n" +
+		"\n" +
+		"`(Unclosed keyword`\n" +
+		"\n" +
+		"# beginning\n" +
+		"Written twice.\n" +
+		"\n" +
+		"# empty\n"
+
+	_, err := parseScript(`test.md`, script)
+	errorList, isErrorList := err.(ScriptErrorList)
+	if !isErrorList {
+		t.Fatalf("Expected a ScriptErrorList but got %v", err)
+	}
+
+	expectedErrors := []string{
+		`test.md:1: text outside of a section`,
+		`test.md:4: unknown ambience directive 'Smell'`,
+		`test.md:6: malformed player command`,
+		`test.md:3: section '# beginning' is empty`,
+		`test.md:8: section '# beginning' has already been defined at line 3`,
+		`test.md:11: section '# empty' is empty`,
+	}
+	if len(errorList) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors but got:\n%v", len(expectedErrors), errorList)
+	}
+	for idx, expectedError := range expectedErrors {
+		if !strings.HasPrefix(errorList[idx].Error(), expectedError) {
+			t.Errorf("Expected error starting with %q but got %q", expectedError, errorList[idx].Error())
+		}
+	}
+}
+
+func TestParseScriptConditionErrors(t *testing.T) {
+	script := "# beginning\n" +
+		"`(Open door) ? owns:key > opened`\n" +
+		"\n" +
+		"`[If: visited:Forest]`\n" +
+		"`[If: flag:seen]`\n" +
+		"\n" +
+		"`(Close door)`\n"
+
+	_, err := parseScript(`test.md`, script)
+	expectedError := "test.md:2: unknown condition 'owns:' (known are 'has:', 'flag:' and 'visited:')\n" +
+		"test.md:5: there is already an `[If: visited:Forest]` for the next narrator line (combine conditions with '&')\n" +
+		"test.md:4: `[If: visited:Forest]` isn't followed by a narrator line"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Expected the errors\n%s\nbut got\n%v", expectedError, err)
+	}
+}
+
+func TestParseScriptIncludes(t *testing.T) {
+	contentDir, err := ioutil.TempDir("", "include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+
+	files := map[string]string{
+		"Common/ending.md": "# credits\n" +
+			"Thanks for playing.\n" +
+			"\n" +
+			"`(Play again) > beginning`\n" +
+			"\n" +
+			"# broken\n" +
+			"`[Smell: roses]`\n" +
+			"Something smells.\n" +
+			"\n" +
+			"# loop\n" +
+			"`[Include: Common/loop.md]`\n",
+		"Common/loop.md": "Round and round.\n" +
+			"\n" +
+			"`[Include: Common/ending.md#loop]`\n",
+	}
+	for fileName, content := range files {
+		os.MkdirAll(filepath.Dir(contentDir+fileName), 0755)
+		if err := ioutil.WriteFile(contentDir+fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scriptPath := contentDir + "Island/script.md"
+	parsed, err := parseScript(scriptPath, "# beginning\n"+
+		"The end.\n"+
+		"\n"+
+		"`[Include: Common/ending.md#credits]`\n"+
+		"\n"+
+		"# problems\n"+
+		"`[Include: Common/ending.md#broken]`\n"+
+		"`[Include: Common/ending.md#missing]`\n"+
+		"`[Include: Common/ending.md#loop]`\n"+
+		"`[Include: Common/../../outside.md]`\n"+
+		"`[Include: /etc/outside.md]`\n")
+
+	beginning := parsed.sectionMap[`beginning`]
+	if len(beginning.lines) != 2 || beginning.lines[1].text != `Thanks for playing.` {
+		t.Fatalf("The included narrator line is missing: %+v", beginning.lines)
+	}
+	if len(beginning.keywords) != 1 || beginning.keywords[0].progressTarget != `beginning` {
+		t.Fatalf("The included keyword is missing: %+v", beginning.keywords)
+	}
+	if pos := beginning.keywords[0].pos; pos.file != contentDir+"Common/ending.md" || pos.line != 4 {
+		t.Fatalf("The included keyword should point into the included file but points at %v", pos)
+	}
+
+	expectedErrors := []string{
+		contentDir + "Common/ending.md:7: unknown ambience directive 'Smell'",
+		scriptPath + ":8: there is no section '# missing' in 'Common/ending.md'",
+		contentDir + "Common/loop.md:3: circular include Common/ending.md#loop -> Common/loop.md -> Common/ending.md#loop",
+		scriptPath + ":10: included file 'Common/../../outside.md' isn't inside the content directory",
+		scriptPath + ":11: included file '/etc/outside.md' isn't inside the content directory",
+	}
+	errorList, _ := err.(ScriptErrorList)
+	if len(errorList) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors but got:\n%v", len(expectedErrors), err)
+	}
+	for idx, expectedError := range expectedErrors {
+		if errorList[idx].Error() != expectedError {
+			t.Errorf("Expected error %q but got %q", expectedError, errorList[idx].Error())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file plays the scenes in a terminal without a window or sound device.
+package scene
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// terminal replaces the window and the speaker while playing with 'RunTerminal'.
+type terminal struct {
+	out io.Writer
+}
+
+// globalTerminal is set while playing in a terminal. The narrator and the ambience directives write to it instead of
+// drawing and playing.
+var globalTerminal *terminal
+
+// printNarratorText writes a narrator line as a paragraph, the markup has already been stripped.
+func (t *terminal) printNarratorText(text string) {
+	fmt.Fprintf(t.out, "%s\n\n", text)
+}
+
+// printAmbience writes an ambience directive as an annotation like it is written in the script.
+func (t *terminal) printAmbience(ambienceCmd *ambienceDirective) {
+	fmt.Fprintf(t.out, "[%s: %s]\n", ambienceCmd.kind, ambienceCmd.argument)
+}
+
+// printWordPanel writes the collected words before the prompt in the word mode, instead of the side panel.
+func (t *terminal) printWordPanel() {
+	fmt.Fprintf(t.out, "(%s)\n", getWordPanelText())
+}
+
+// getStartSceneName returns the scene the main menu's 'Start' item leads to.
+func getStartSceneName() string {
+	for _, menuItem := range globalMenuItems {
+		if menuItem.Text == `Start` {
+			return menuItem.sceneName
+		}
+	}
+	return ``
+}
+
+// RunTerminal plays the game by reading commands line by line from in and writing the narrator's text to out.
+//
+// It uses the same scene logic as the window: the script queue, keywords, 'go' and 'look'. Narrator lines are
+// delivered without waiting for Enter, `[Wait: ...]` doesn't pause and `[Idle: ...]` lines aren't shown. Ambience
+// directives are written as annotations instead of being played. 'quit' or the end of the input stop the game.
+func RunTerminal(in io.Reader, out io.Writer) error {
+	globalTerminal = &terminal{out: out}
+	defer func() {
+		globalTerminal = nil
+	}()
+
+	LoadFilesToSceneMap()
+	GlobalCurrentScene = getStartSceneName()
+	globalUndoSnapshots = nil
+
+	input := bufio.NewScanner(in)
+	for {
+		isWaitingForCommand, err := deliverUntilCommand()
+		if err != nil {
+			return err
+		}
+		if !isWaitingForCommand {
+			s := GlobalScenes[GlobalCurrentScene]
+			fmt.Fprintf(out, "(The section '# %s' of '%s' doesn't wait for any command.)\n", s.progress, s.Name)
+			return nil
+		}
+
+		fmt.Fprint(out, "> ")
+		if !input.Scan() {
+			fmt.Fprintln(out)
+			return input.Err()
+		}
+		playerInput := strings.TrimSpace(input.Text())
+		if playerInput == `quit` {
+			return nil
+		}
+		GlobalScenes[GlobalCurrentScene].handlePlayerInput(playerInput)
+	}
+}
+
+// deliverUntilCommand delivers the queued narrator lines of the current scene, following jumps into other scenes,
+// and returns whether the game waits for a player command afterwards.
+func deliverUntilCommand() (bool, error) {
+	for {
+		s := GlobalScenes[GlobalCurrentScene]
+		if s == nil {
+			return false, fmt.Errorf("there is no scene '%s'", GlobalCurrentScene)
+		}
+		if globalPreviousScene != GlobalCurrentScene {
+			s.countVisit()
+			globalPreviousScene = GlobalCurrentScene
+		}
+
+		if len(s.script.responseQueue) == 0 && !s.hasKeywords() {
+			if err := s.loadActiveSection(); err != nil {
+				s.reportScriptError(err)
+				return false, err
+			}
+		}
+		// A wait has already been written as an annotation, the terminal doesn't pause for it
+		for GlobalCurrentScene == s.Name && s.deliverNextResponse() {
+			s.script.waitUntil = time.Time{}
+		}
+		if GlobalCurrentScene != s.Name {
+			continue
+		}
+		if s.hasKeywords() && s.isWordMode() {
+			globalTerminal.printWordPanel()
+		}
+		return s.hasKeywords(), nil
+	}
+}
@@ -0,0 +1,39 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunTerminal(t *testing.T) {
+	previousScenes, previousCurrentScene, previousPreviousScene := GlobalScenes, GlobalCurrentScene, globalPreviousScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalPreviousScene = previousScenes, previousCurrentScene, previousPreviousScene
+	}()
+
+	var out strings.Builder
+	if err := RunTerminal(strings.NewReader("inspect reflection\ngo North\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if globalTerminal != nil {
+		t.Errorf("Expected the terminal to be reset after the game")
+	}
+
+	expectedParts := []string{
+		"[Audio: Wave.ogg]\nYou open your eyes.\n\n",
+		"> You walk closer to whatever it is that caught your eye.\n\n",
+		"A compass.\n\n",
+		"> [Audio: Celesta.ogg]\nBeginning of the Forest scene.\n\n",
+	}
+	output := out.String()
+	for _, expectedPart := range expectedParts {
+		index := strings.Index(output, expectedPart)
+		if index < 0 {
+			t.Fatalf("Expected the output to contain\n%q\nbut got\n%q", expectedPart, output)
+		}
+		output = output[index+len(expectedPart):]
+	}
+	if GlobalCurrentScene != `Forest` {
+		t.Errorf("Expected to have gone to 'Forest' but the current scene is '%s'", GlobalCurrentScene)
+	}
+}
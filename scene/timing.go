@@ -0,0 +1,97 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the timed ambience directives which advance the script without the player pressing Enter.
+package scene
+
+import (
+	"regexp"
+	"time"
+)
+
+// clock tells the time for the timed directives. Tests replace 'globalClock' to control it.
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var globalClock clock = systemClock{}
+
+// durationArgumentRegexp matches the arguments of the timed directives, e.g. `[Wait: 2s]` or `[Idle: 1.5m]`.
+var durationArgumentRegexp = regexp.MustCompile(`^\d+(?:\.\d+)?(?:ms|s|m)$`)
+
+const (
+	// waitDirective pauses before the narrator line below it is shown, Enter is ignored meanwhile
+	waitDirective = `Wait`
+	// autoAdvanceDirective moves on to the next narrator line after the given time without Enter
+	autoAdvanceDirective = `AutoAdvance`
+	// idleDirective makes the narrator line below it part of the section no matter where it is written. It is shown
+	// once the player hasn't typed anything for the given time while the section waits for a command.
+	idleDirective = `Idle`
+)
+
+// getDirectiveDuration returns the duration of the first directive of the given kind or 0 if there is none.
+func getDirectiveDuration(ambienceCmdSlice []*ambienceDirective, kind string) time.Duration {
+	for _, ambienceCmd := range ambienceCmdSlice {
+		if ambienceCmd.kind == kind {
+			// The argument has been validated by 'durationArgumentRegexp' while parsing
+			duration, _ := time.ParseDuration(ambienceCmd.argument)
+			return duration
+		}
+	}
+	return 0
+}
+
+// isWaiting returns whether a `[Wait: ...]` directive is pausing the script.
+func (s *Scene) isWaiting() bool {
+	return !s.script.waitUntil.IsZero()
+}
+
+// noteActivity restarts the idle time, it's called whenever the player types or presses Enter.
+func (s *Scene) noteActivity() {
+	s.script.lastActivity = globalClock.Now()
+	s.script.deliveredIdleResponses = nil
+}
+
+// advanceTimedScript delivers the next response if a timed directive is due and returns whether it did.
+func (s *Scene) advanceTimedScript() bool {
+	now := globalClock.Now()
+
+	if s.isWaiting() {
+		if now.Before(s.script.waitUntil) {
+			return false
+		}
+		s.script.waitUntil = time.Time{}
+		return s.deliverNextResponse()
+	}
+
+	if !s.script.autoAdvanceAt.IsZero() {
+		if now.Before(s.script.autoAdvanceAt) {
+			return false
+		}
+		s.script.autoAdvanceAt = time.Time{}
+		return s.deliverNextResponse()
+	}
+
+	if len(s.script.responseQueue) > 0 || !s.hasKeywords() {
+		return false
+	}
+	for idx, response := range s.script.idleResponses {
+		if s.script.deliveredIdleResponses[idx] || !response.condition.isMet() ||
+			now.Sub(s.script.lastActivity) < getDirectiveDuration(response.ambienceCmdSlice, idleDirective) {
+			continue
+		}
+		if s.script.deliveredIdleResponses == nil {
+			s.script.deliveredIdleResponses = make(map[int]bool)
+		}
+		s.script.deliveredIdleResponses[idx] = true
+		s.script.responseQueue = append(s.script.responseQueue, response)
+		return s.deliverNextResponse()
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package scene
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(duration time.Duration) {
+	c.now = c.now.Add(duration)
+}
+
+func TestTimedDirectives(t *testing.T) {
+	testClock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	globalClock = testClock
+	defer func() {
+		globalClock = systemClock{}
+	}()
+
+	s := getSceneObjectWithDefaults()
+	s.script.parsed, s.script.parseErr = parseScript(`test.md`, "# beginning\n"+
+		"`[AutoAdvance: 4s]`\n"+
+		"The sun rises.\n"+
+		"\n"+
+		"`[Wait: 2s]`\n"+
+		"Birds start to sing.\n"+
+		"\n"+
+		"`(Listen)`\n"+
+		"You listen.\n"+
+		"\n"+
+		"`[Idle: 30s]`\n"+
+		"The birds are waiting for you.\n")
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	s.deliverNextResponse()
+	if globalNarrator.currentTextString != `The sun rises.` {
+		t.Fatalf("Expected the first line but got %q", globalNarrator.currentTextString)
+	}
+
+	testClock.advance(3 * time.Second)
+	if s.advanceTimedScript() {
+		t.Fatalf("The script advanced before the auto advance was due")
+	}
+	testClock.advance(time.Second)
+	if !s.advanceTimedScript() || !s.isWaiting() {
+		t.Fatalf("Expected the auto advance to start waiting for the second line")
+	}
+	if globalNarrator.currentTextString != `The sun rises.` {
+		t.Fatalf("The second line has been shown before the wait was over: %q", globalNarrator.currentTextString)
+	}
+
+	testClock.advance(2 * time.Second)
+	if !s.advanceTimedScript() || globalNarrator.currentTextString != `Birds start to sing.` {
+		t.Fatalf("Expected the second line after waiting but got %q", globalNarrator.currentTextString)
+	}
+
+	s.noteActivity()
+	testClock.advance(29 * time.Second)
+	if s.advanceTimedScript() {
+		t.Fatalf("The idle line has been shown too early")
+	}
+	testClock.advance(time.Second)
+	if !s.advanceTimedScript() || globalNarrator.currentTextString != `The birds are waiting for you.` {
+		t.Fatalf("Expected the idle line but got %q", globalNarrator.currentTextString)
+	}
+	testClock.advance(time.Minute)
+	if s.advanceTimedScript() {
+		t.Fatalf("The idle line should only be shown once until the player does something")
+	}
+
+	s.handlePlayerCommand(`listen`)
+	if globalNarrator.currentTextString != `You listen.` {
+		t.Fatalf("The idle line shouldn't have become part of the keyword's answer: %q", globalNarrator.currentTextString)
+	}
+}
@@ -0,0 +1,155 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file plays transcripts of player commands against the content and compares what the narrator writes.
+package scene
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// transcriptWildcard stands for any number of output lines in a transcript.
+const transcriptWildcard = `...`
+
+// transcriptStep is a player command and the output expected after it. The first step has no command, its output is
+// what is shown when the scene is entered.
+type transcriptStep struct {
+	command  string
+	line     int
+	expected []transcriptLine
+}
+
+type transcriptLine struct {
+	text string
+	line int
+}
+
+// parseTranscript reads a transcript which is written the way '-tty' shows the game:
+// - `> command` is typed by the player
+// - every other line is expected output, i.e. a narrator line without its markup or an annotation like
+// `[Audio: Wave.ogg]`
+// - `...` skips any number of output lines
+// - empty lines and lines starting with '#' are ignored
+func parseTranscript(content string) []transcriptStep {
+	steps := []transcriptStep{{line: 1}}
+	lines := strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		switch {
+		case line == `` || strings.HasPrefix(line, `#`):
+			continue
+		case strings.HasPrefix(line, `>`):
+			steps = append(steps, transcriptStep{command: strings.TrimSpace(line[1:]), line: i + 1})
+		default:
+			step := &steps[len(steps)-1]
+			if line == transcriptWildcard && len(step.expected) > 0 &&
+				step.expected[len(step.expected)-1].text == transcriptWildcard {
+				continue
+			}
+			step.expected = append(step.expected, transcriptLine{text: line, line: i + 1})
+		}
+	}
+	return steps
+}
+
+// getOutputLines splits the terminal output into its non-empty lines.
+func getOutputLines(output string) []string {
+	var outputLines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimRight(line, " \t"); line != `` {
+			outputLines = append(outputLines, line)
+		}
+	}
+	return outputLines
+}
+
+// compareOutput returns a message describing the first difference between the expected and the actual output or an
+// empty string if they match. The line is where the difference is reported, unexpected output is reported at the last
+// line of the step.
+func (step *transcriptStep) compareOutput(output string) (msg string, line int) {
+	context := `when entering the scene`
+	if step.command != `` {
+		context = fmt.Sprintf("after '> %s'", step.command)
+	}
+
+	outputLines := getOutputLines(output)
+	next := 0
+	for i, expected := range step.expected {
+		if expected.text == transcriptWildcard {
+			if i+1 == len(step.expected) {
+				return ``, 0
+			}
+			for next < len(outputLines) && outputLines[next] != step.expected[i+1].text {
+				next++
+			}
+			continue
+		}
+		if next >= len(outputLines) {
+			return fmt.Sprintf("%s expected '%s' but there was no more output", context, expected.text), expected.line
+		}
+		if outputLines[next] != expected.text {
+			return fmt.Sprintf("%s expected '%s' but got '%s'", context, expected.text, outputLines[next]),
+				expected.line
+		}
+		next++
+	}
+	if next < len(outputLines) {
+		lastLine := step.line
+		if len(step.expected) > 0 {
+			lastLine = step.expected[len(step.expected)-1].line
+		}
+		return fmt.Sprintf("%s got the unexpected output '%s'", context, outputLines[next]), lastLine
+	}
+	return ``, 0
+}
+
+// runTranscript plays the transcript at path in the scene whose folder contains it, starting with a freshly loaded
+// game. It returns the first divergence from the transcript as a '*ScriptError'.
+//
+// The random number generator is seeded the same way every time so random variants and fallback lines can be part of
+// a transcript.
+func runTranscript(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	steps := parseTranscript(string(content))
+
+	var output strings.Builder
+	globalTerminal = &terminal{out: &output}
+	defer func() {
+		globalTerminal = nil
+	}()
+
+	LoadFilesToSceneMap()
+	globalWorld.variables = make(map[string]string)
+	globalPlayer.items, globalPlayer.wordInventory = nil, nil
+	SeedRandom(1)
+	GlobalCurrentScene = filepath.Base(filepath.Dir(path))
+	globalPreviousScene = ``
+	globalUndoSnapshots = nil
+
+	isWaitingForCommand := false
+	for _, step := range steps {
+		if step.command != `` {
+			if !isWaitingForCommand {
+				s := GlobalScenes[GlobalCurrentScene]
+				return &ScriptError{File: path, Line: step.line, Msg: fmt.Sprintf(
+					"'> %s' can't be typed because the section '# %s' of '%s' doesn't wait for any command",
+					step.command, s.progress, s.Name)}
+			}
+			GlobalScenes[GlobalCurrentScene].handlePlayerInput(step.command)
+		}
+		if isWaitingForCommand, err = deliverUntilCommand(); err != nil {
+			return &ScriptError{File: path, Line: step.line, Msg: err.Error()}
+		}
+		if msg, line := step.compareOutput(output.String()); msg != `` {
+			return &ScriptError{File: path, Line: line, Msg: msg}
+		}
+		output.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTranscripts plays every '*.transcript' file inside the scene folders.
+func TestTranscripts(t *testing.T) {
+	previousScenes, previousCurrentScene, previousPreviousScene := GlobalScenes, GlobalCurrentScene, globalPreviousScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalPreviousScene = previousScenes, previousCurrentScene, previousPreviousScene
+	}()
+
+	transcriptPaths, err := filepath.Glob(ContentDir + "*/*.transcript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcriptPaths) == 0 {
+		t.Fatalf("Expected transcripts inside '%s'", ContentDir)
+	}
+	for _, transcriptPath := range transcriptPaths {
+		transcriptPath := transcriptPath
+		t.Run(strings.TrimPrefix(transcriptPath, ContentDir), func(t *testing.T) {
+			if err := runTranscript(transcriptPath); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTranscriptReportsFirstDivergence(t *testing.T) {
+	previousScenes, previousCurrentScene, previousPreviousScene := GlobalScenes, GlobalCurrentScene, globalPreviousScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalPreviousScene = previousScenes, previousCurrentScene, previousPreviousScene
+	}()
+
+	transcriptDir, err := ioutil.TempDir("", "transcript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(transcriptDir)
+	// The folder name selects the scene the transcript is played in
+	os.Mkdir(transcriptDir+"/Beach", 0755)
+
+	tests := []struct {
+		transcript    string
+		expectedError string
+	}{
+		{
+			"...\n" +
+				"> inspect reflection\n" +
+				"You walk closer to whatever it is that caught your eye.\n" +
+				"It was glass.\n" +
+				"A compass.\n",
+			":4: after '> inspect reflection' expected 'It was glass.' but got 'It was glass that reflected",
+		},
+		{
+			"...\n" +
+				"> inspect reflection\n" +
+				"You walk closer to whatever it is that caught your eye.\n",
+			":3: after '> inspect reflection' got the unexpected output 'It was glass",
+		},
+		{
+			"...\n" +
+				"> inspect reflection\n" +
+				"...\n" +
+				"You pick up the compass.\n",
+			":4: after '> inspect reflection' expected 'You pick up the compass.' but there was no more output",
+		},
+		{
+			"[Audio: Wave.ogg]\n" +
+				"You close your eyes.\n" +
+				"...\n",
+			":2: when entering the scene expected 'You close your eyes.' but got 'You open your eyes.'",
+		},
+	}
+	for _, test := range tests {
+		transcriptPath := transcriptDir + "/Beach/test.transcript"
+		if err := ioutil.WriteFile(transcriptPath, []byte(test.transcript), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := runTranscript(transcriptPath)
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("Expected the error '%s' for\n%s\nbut got '%v'", test.expectedError, test.transcript, err)
+		}
+	}
+}
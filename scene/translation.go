@@ -0,0 +1,87 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the language setting and the translations of the texts shown by the engine itself.
+package scene
+
+// globalLanguage selects the localized content files (e.g. 'de' for 'script.de.md'), empty means the default files.
+var globalLanguage string
+
+// SetLanguage selects the language of the content and the engine's texts. It has to be called before
+// 'LoadFilesToSceneMap'.
+//
+// Sections, map config entries and texts which haven't been translated are shown in the default language.
+func SetLanguage(language string) {
+	if language == defaultLanguage {
+		language = ``
+	}
+	globalLanguage = language
+}
+
+// defaultLanguage is the language of the content files without a language in their name
+const defaultLanguage = `en`
+
+// translations maps a language to the translations of the engine's texts which are written in the default language.
+//
+// Texts containing '%s' are used as format strings and '{input}' is replaced with the player's input.
+var translations = map[string]map[string]string{
+	`de`: {
+		// Hints
+		`Press Enter to continue.`:            `Drücke Enter, um fortzufahren.`,
+		`Write a command and press Enter.`:    `Schreibe einen Befehl und drücke Enter.`,
+		`The game has been saved in slot %d.`: `Das Spiel wurde in Spielstand %d gespeichert.`,
+		// Main menu
+		`Start`:    `Starten`,
+		`Continue`: `Fortsetzen`,
+		`Load`:     `Laden`,
+		`Quit`:     `Beenden`,
+		// Main menu messages
+		`There is no game to continue yet.`:  `Es gibt noch kein Spiel zum Fortsetzen.`,
+		`The saved game couldn't be loaded.`: `Der Spielstand konnte nicht geladen werden.`,
+		// Load screen
+		`Autosave`:            `Automatisch gespeichert`,
+		`Slot %d`:             `Spielstand %d`,
+		`empty`:               `leer`,
+		`played %s, saved %s`: `gespielt %s, gespeichert %s`,
+		// Built-in verbs
+		`Where do you want to go? (Enter a direction: e.g. North)`: `Wohin möchtest du gehen? (Gib eine Richtung ein: z.B. North)`,
+		`You can't go to '%s'! (Enter a direction: e.g. North)`:    `Du kannst nicht nach '%s' gehen! (Gib eine Richtung ein: z.B. North)`,
+		`You can't look to '%s'! (Enter a direction: e.g. North)`:  `Du kannst nicht nach '%s' schauen! (Gib eine Richtung ein: z.B. North)`,
+		`You don't carry anything.`:                                `Du trägst nichts bei dir.`,
+		`You carry: %s.`:                                           `Du trägst: %s.`,
+		`What do you want to take?`:                                `Was möchtest du nehmen?`,
+		`What do you want to drop?`:                                `Was möchtest du ablegen?`,
+		`You already carry the %s.`:                                `Du trägst %s schon bei dir.`,
+		`You can't take the %s.`:                                   `Du kannst %s nicht nehmen.`,
+		`You take the %s.`:                                         `Du nimmst %s.`,
+		`You drop the %s.`:                                         `Du legst %s ab.`,
+		`What do you want to open?`:                                `Was möchtest du öffnen?`,
+		`What do you want to use?`:                                 `Was möchtest du benutzen?`,
+		`What do you want to use the %s on?`:                       `Worauf möchtest du %s anwenden?`,
+		`Nothing happens when you use the %s on the %s.`:           `Nichts passiert, als du %s auf %s anwendest.`,
+		`You see nothing special about the %s.`:                    `Du siehst nichts Besonderes an %s.`,
+		`You don't know the word '%s' yet.`:                        `Du kennst das Wort '%s' noch nicht.`,
+		`Words: %s`:                                                `Wörter: %s`,
+		`Words:`:                                                   `Wörter:`,
+		`There is nothing to undo.`:                                `Es gibt nichts rückgängig zu machen.`,
+		// Keyword matching
+		`Did you mean %s?`: `Meintest du %s?`,
+		`%s or %s`:         `%s oder %s`,
+		// Fallback lines
+		`I don't understand '{input}'.`:                                `Ich verstehe '{input}' nicht.`,
+		`You think about how to '{input}', but nothing comes to mind.`: `Du überlegst, wie du '{input}' könntest, aber dir fällt nichts ein.`,
+		`'{input}'? Nothing happens.`:                                  `'{input}'? Nichts passiert.`,
+	},
+}
+
+// translate returns the text in the selected language or the text itself if there is no translation.
+func translate(str string) string {
+	if translation, isTranslated := translations[globalLanguage][str]; isTranslated {
+		return translation
+	}
+	return str
+}
+
+// translationRunes are drawn by the text atlases besides ASCII, these are the letters of the translations and
+// typographic punctuation used in the scripts.
+var translationRunes = []rune(`ÄÖÜäöüßéèà’‘“”„–—…`)
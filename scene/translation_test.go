@@ -0,0 +1,72 @@
+package scene
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalizedContent(t *testing.T) {
+	SetLanguage(`de`)
+	defer SetLanguage(``)
+
+	contentDir, err := ioutil.TempDir("", "translation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contentDir)
+	contentDir += "/"
+
+	files := map[string]string{
+		"Island/script.md": "# beginning\n" +
+			"The sun is setting.\n" +
+			"\n" +
+			"`(Swim) > sea`\n" +
+			"\n" +
+			"# sea\n" +
+			"The water is cold.\n",
+		"Island/script.de.md":      "# beginning\nDie Sonne geht unter.\n\n`(Schwimmen) > sea`\n",
+		"Island/script.fr.md":      "# beginning\nLe soleil se couche.\n",
+		"Island/mapConfig.json":    `{"directions": {"north": "Island"}, "look": "An island."}`,
+		"Island/mapConfig.de.json": `{"look": "Eine Insel."}`,
+	}
+	for fileName, content := range files {
+		os.MkdirAll(filepath.Dir(contentDir+fileName), 0755)
+		if err := ioutil.WriteFile(contentDir+fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := getSceneObjectWithDefaults()
+	s.Name = `Island`
+	if err := s.loadSceneFiles(contentDir); err != nil {
+		t.Fatal(err)
+	}
+	if s.script.parseErr != nil {
+		t.Fatal(s.script.parseErr)
+	}
+	if s.mapConfig.Look != `Eine Insel.` || s.mapConfig.Directions[`north`] != `Island` {
+		t.Fatalf("Expected the translated look and the default directions but got %+v", s.mapConfig)
+	}
+
+	if err := s.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	s.deliverNextResponse()
+	if globalNarrator.currentTextString != `Die Sonne geht unter.` {
+		t.Fatalf("Expected the translated section but got %q", globalNarrator.currentTextString)
+	}
+	s.handlePlayerCommand(`schwimmen`)
+	if globalNarrator.currentTextString != `The water is cold.` {
+		t.Fatalf("Expected the untranslated section in the default language but got %q",
+			globalNarrator.currentTextString)
+	}
+
+	if translate(`Press Enter to continue.`) != `Drücke Enter, um fortzufahren.` {
+		t.Fatalf("The hint hasn't been translated")
+	}
+	if getClarifyingQuestion([]string{`a`, `b`}) != `Meintest du 'a' oder 'b'?` {
+		t.Fatalf("The clarifying question hasn't been translated: %q", getClarifyingQuestion([]string{`a`, `b`}))
+	}
+}
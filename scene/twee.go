@@ -0,0 +1,391 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file converts stories written in Twine (Twee 3 source, https://github.com/iftechfoundation/twine-specs) into
+// scene folders.
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tweePassage is a passage of a Twee file, e.g. ':: Start [scene:Beach] {"position":"100,100"}' and the text below.
+type tweePassage struct {
+	name string
+	tags []string
+	// line is the line of the passage header in the Twee file
+	line  int
+	lines []string
+}
+
+// tweeStory contains the passages of a Twee file besides the special ones.
+type tweeStory struct {
+	filePath string
+	title    string
+	// start is the name of the passage the story starts with
+	start    string
+	passages []*tweePassage
+}
+
+// tweeSceneTagPrefix puts a passage into a scene folder other than the default one, e.g. 'scene:Lighthouse'.
+const tweeSceneTagPrefix = `scene:`
+
+var (
+	tweeHeaderRegexp = regexp.MustCompile(`^::\s*(.*?)\s*(?:\[(.*?)\])?\s*(?:\{.*\})?\s*$`)
+	tweeLinkRegexp   = regexp.MustCompile(`\[\[(.+?)\]\]`)
+	// tweeMacroRegexps find the macros of the common story formats which have no counterpart in the scripts
+	tweeMacroRegexps = []*regexp.Regexp{
+		// SugarCube, e.g. '<<set $torch to true>>'
+		regexp.MustCompile(`<<.*?>>`),
+		// Harlowe, e.g. '(set: $torch to true)' or '(if: $torch)'
+		regexp.MustCompile(`\([\w-]+:`),
+		// Snowman, e.g. '<% s.torch = true %>'
+		regexp.MustCompile(`<%.*?%>`),
+		// Story variables, e.g. '$torch'
+		regexp.MustCompile(`\$\w+`),
+	}
+	nonWordRunsRegexp = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// readTweeStory splits the Twee source into passages and reads the story title and start passage.
+func readTweeStory(filePath, content string) (*tweeStory, ScriptErrorList) {
+	story := &tweeStory{filePath: filePath}
+	var problems ScriptErrorList
+	var allPassages []*tweePassage
+
+	var current *tweePassage
+	for idx, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if headerMatch := tweeHeaderRegexp.FindStringSubmatch(line); headerMatch != nil {
+			current = &tweePassage{name: headerMatch[1], tags: strings.Fields(headerMatch[2]), line: idx + 1}
+			allPassages = append(allPassages, current)
+			continue
+		}
+		if current != nil {
+			current.lines = append(current.lines, line)
+		}
+	}
+
+	for _, passage := range allPassages {
+		text := strings.TrimSpace(strings.Join(passage.lines, "\n"))
+		switch {
+		case passage.name == `StoryTitle`:
+			story.title = text
+		case passage.name == `StoryData`:
+			var storyData struct {
+				Start string
+			}
+			if err := json.Unmarshal([]byte(text), &storyData); err != nil {
+				problems = append(problems, &ScriptError{File: filePath, Line: passage.line, Msg: "StoryData: " + err.Error()})
+			}
+			story.start = storyData.Start
+		case hasTweeTag(passage, `script`) || hasTweeTag(passage, `stylesheet`) || hasTweeTag(passage, `widget`):
+			problems = append(problems, &ScriptError{
+				File: filePath,
+				Line: passage.line,
+				Msg:  "passage '" + passage.name + "' contains story JavaScript, styles or widgets which aren't supported",
+			})
+		default:
+			story.passages = append(story.passages, passage)
+		}
+	}
+
+	if len(story.passages) == 0 {
+		problems = append(problems, &ScriptError{File: filePath, Msg: "there are no passages"})
+	} else if story.start == `` {
+		story.start = story.passages[0].name
+		for _, passage := range story.passages {
+			if passage.name == `Start` {
+				story.start = passage.name
+			}
+		}
+	}
+	return story, problems
+}
+
+func hasTweeTag(passage *tweePassage, tag string) bool {
+	for _, passageTag := range passage.tags {
+		if passageTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// getTweeSceneName returns the scene folder of the passage.
+func getTweeSceneName(passage *tweePassage, defaultSceneName string) string {
+	for _, tag := range passage.tags {
+		if strings.HasPrefix(tag, tweeSceneTagPrefix) {
+			return strings.TrimPrefix(tag, tweeSceneTagPrefix)
+		}
+	}
+	return defaultSceneName
+}
+
+// isTweeSceneName returns whether the scene name can be used as a folder inside the content directory, i.e. it can't
+// point somewhere else like 'scene:../Beach' does.
+func isTweeSceneName(sceneName string) bool {
+	return sceneName != `` && !strings.ContainsAny(sceneName, `/\`) && !strings.Contains(sceneName, `..`)
+}
+
+// getTweeSectionName turns a passage name like 'The Old Cupboard' into a section name like 'the_old_cupboard'.
+func getTweeSectionName(passageName string) string {
+	sectionName := strings.Trim(nonWordRunsRegexp.ReplaceAllString(strings.ToLower(passageName), `_`), `_`)
+	if sectionName == `` {
+		return `passage`
+	}
+	return sectionName
+}
+
+// getTweeSceneNameFromTitle turns a story title like 'The Lighthouse' into a scene name like 'TheLighthouse'.
+func getTweeSceneNameFromTitle(title string) string {
+	var sceneName string
+	for _, word := range nonWordRunsRegexp.Split(title, -1) {
+		if firstRune, size := utf8.DecodeRuneInString(word); size > 0 {
+			sceneName += string(unicode.ToUpper(firstRune)) + word[size:]
+		}
+	}
+	if sceneName == `` {
+		return `Twine`
+	}
+	return sceneName
+}
+
+// parseTweeLink splits the inside of a '[[link]]' into the text shown and the passage it leads to.
+//
+// The forms are 'Target', 'Text|Target', 'Text->Target' and 'Target<-Text'.
+func parseTweeLink(link string) (text, target string) {
+	if idx := strings.LastIndex(link, `->`); idx >= 0 {
+		return link[:idx], link[idx+2:]
+	}
+	if idx := strings.Index(link, `<-`); idx >= 0 {
+		return link[idx+2:], link[:idx]
+	}
+	if idx := strings.LastIndex(link, `|`); idx >= 0 {
+		return link[:idx], link[idx+1:]
+	}
+	return link, link
+}
+
+// convertTweeStory returns the content of the script.md of every scene by scene name.
+//
+// The start passage becomes '# beginning' of its scene, the other scenes begin with their first passage. Links become
+// keywords below the passage text and Twine macros are kept as comments and reported.
+func convertTweeStory(story *tweeStory, defaultSceneName string) (map[string]string, ScriptErrorList) {
+	var problems ScriptErrorList
+	addProblem := func(line int, format string, args ...interface{}) {
+		problems = append(problems, &ScriptError{File: story.filePath, Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	// Every passage gets its scene and section name before the links between them are converted
+	sceneNames := make(map[string]string)
+	sectionNames := make(map[string]string)
+	// isSectionNameTaken contains 'Scene#section' for every section name used so far
+	isSectionNameTaken := make(map[string]bool)
+	var sceneOrder []string
+	for _, passage := range story.passages {
+		if _, isDuplicate := sceneNames[passage.name]; isDuplicate {
+			addProblem(passage.line, "passage '%s' has already been defined, it is skipped", passage.name)
+			continue
+		}
+		sceneName := getTweeSceneName(passage, defaultSceneName)
+		if !isTweeSceneName(sceneName) {
+			addProblem(passage.line, "passage '%s' is tagged with the invalid scene name '%s', it is put into '%s'",
+				passage.name, sceneName, defaultSceneName)
+			sceneName = defaultSceneName
+		}
+		sceneNames[passage.name] = sceneName
+		// 'beginning' is reserved for the first passage of every scene
+		if !isSectionNameTaken[sceneName+`#beginning`] {
+			isSectionNameTaken[sceneName+`#beginning`] = true
+			sceneOrder = append(sceneOrder, sceneName)
+		}
+	}
+	isBeginningTaken := make(map[string]bool)
+	if startSceneName, isKnown := sceneNames[story.start]; isKnown {
+		sectionNames[story.start] = `beginning`
+		isBeginningTaken[startSceneName] = true
+	} else {
+		addProblem(0, "the start passage '%s' doesn't exist", story.start)
+	}
+	for _, passage := range story.passages {
+		sceneName := sceneNames[passage.name]
+		if _, isNamed := sectionNames[passage.name]; isNamed {
+			continue
+		}
+		if !isBeginningTaken[sceneName] {
+			sectionNames[passage.name] = `beginning`
+			isBeginningTaken[sceneName] = true
+			continue
+		}
+		sectionName := getTweeSectionName(passage.name)
+		for suffix := 2; isSectionNameTaken[sceneName+`#`+sectionName]; suffix++ {
+			sectionName = fmt.Sprintf("%s_%d", getTweeSectionName(passage.name), suffix)
+		}
+		isSectionNameTaken[sceneName+`#`+sectionName] = true
+		sectionNames[passage.name] = sectionName
+	}
+
+	scripts := make(map[string]*strings.Builder)
+	for _, sceneName := range sceneOrder {
+		scripts[sceneName] = &strings.Builder{}
+	}
+	isWritten := make(map[string]bool)
+	for _, passage := range story.passages {
+		if isWritten[passage.name] {
+			continue
+		}
+		isWritten[passage.name] = true
+		sceneName := sceneNames[passage.name]
+		script := scripts[sceneName]
+
+		var paragraphs, keywords []string
+		var paragraphLines []string
+		paragraphLine := passage.line + 1
+		flushParagraph := func() {
+			if len(paragraphLines) == 0 {
+				return
+			}
+			paragraph := strings.Join(paragraphLines, "\n")
+			paragraphLines = nil
+
+			for _, linkMatch := range tweeLinkRegexp.FindAllStringSubmatch(paragraph, -1) {
+				text, target := parseTweeLink(linkMatch[1])
+				targetSceneName, isKnown := sceneNames[target]
+				if !isKnown {
+					addProblem(paragraphLine, "passage '%s' links to the missing passage '%s'", passage.name, target)
+					keywords = append(keywords, "`("+text+")`")
+					continue
+				}
+				progressTarget := sectionNames[target]
+				if targetSceneName != sceneName {
+					progressTarget = targetSceneName + `#` + progressTarget
+				}
+				keywords = append(keywords, "`("+text+") > "+progressTarget+"`")
+			}
+			paragraph = tweeLinkRegexp.ReplaceAllStringFunc(paragraph, func(link string) string {
+				text, _ := parseTweeLink(link[2 : len(link)-2])
+				return text
+			})
+
+			var macros []string
+			for _, macroRegexp := range tweeMacroRegexps {
+				macros = append(macros, macroRegexp.FindAllString(paragraph, -1)...)
+			}
+			isDirective := strings.HasPrefix(paragraph, "`") || sectionHeadingRegexp.MatchString(paragraph)
+			if len(macros) > 0 || isDirective {
+				for _, macro := range macros {
+					addProblem(paragraphLine, "passage '%s' uses the unsupported Twine macro '%s', "+
+						"the paragraph is kept as a comment", passage.name, macro)
+				}
+				if isDirective {
+					addProblem(paragraphLine, "passage '%s' has a paragraph which would be read as a heading or "+
+						"directive, it is kept as a comment", passage.name)
+				}
+				paragraph = "<!-- Twine: " + strings.Replace(paragraph, `-->`, `-- >`, -1) + " -->"
+			}
+			if strings.TrimSpace(paragraph) != `` {
+				paragraphs = append(paragraphs, paragraph)
+			}
+		}
+		for idx, line := range passage.lines {
+			if strings.TrimSpace(line) == `` {
+				flushParagraph()
+				paragraphLine = passage.line + idx + 2
+				continue
+			}
+			paragraphLines = append(paragraphLines, strings.TrimSpace(line))
+		}
+		flushParagraph()
+
+		// Comments alone would leave the section empty
+		hasText := false
+		for _, paragraph := range paragraphs {
+			hasText = hasText || !strings.HasPrefix(paragraph, `<!--`)
+		}
+		if !hasText && len(keywords) == 0 {
+			addProblem(passage.line, "passage '%s' has no text, its name is used instead", passage.name)
+			paragraphs = append(paragraphs, passage.name)
+		}
+
+		if script.Len() > 0 {
+			script.WriteString("\n")
+		}
+		script.WriteString("# " + sectionNames[passage.name] + "\n")
+		for _, paragraph := range append(paragraphs, keywords...) {
+			script.WriteString(paragraph + "\n\n")
+		}
+	}
+
+	scriptContents := make(map[string]string)
+	for sceneName, script := range scripts {
+		scriptContents[sceneName] = strings.TrimRight(script.String(), "\n") + "\n"
+	}
+	return scriptContents, problems
+}
+
+// ImportTwee converts a Twine story written as Twee 3 source into scene folders inside contentDir.
+//
+// Passages are put into the scene named like the story title unless sceneName is given or they are tagged with e.g.
+// 'scene:Lighthouse'. Every scene folder gets a 'script.md' and an empty 'mapConfig.json'. Existing files aren't
+// overwritten.
+//
+// The returned problems contain everything that couldn't be converted. If a file already exists an error is returned
+// before anything is written.
+func ImportTwee(tweePath, contentDir, defaultSceneName string) (ScriptErrorList, error) {
+	content, err := ioutil.ReadFile(tweePath)
+	if err != nil {
+		return nil, err
+	}
+
+	story, problems := readTweeStory(tweePath, string(content))
+	if defaultSceneName == `` {
+		defaultSceneName = getTweeSceneNameFromTitle(story.title)
+	}
+	if !isTweeSceneName(defaultSceneName) {
+		return problems, fmt.Errorf("'%s' can't be used as a scene name", defaultSceneName)
+	}
+	scripts, conversionProblems := convertTweeStory(story, defaultSceneName)
+	problems = append(problems, conversionProblems...)
+
+	var sceneNames []string
+	for sceneName := range scripts {
+		sceneNames = append(sceneNames, sceneName)
+		for _, fileName := range []string{`script.md`, `mapConfig.json`} {
+			if _, err := os.Stat(contentDir + sceneName + `/` + fileName); err == nil {
+				return problems, fmt.Errorf("'%s' already exists", contentDir+sceneName+`/`+fileName)
+			}
+		}
+	}
+	sort.Strings(sceneNames)
+
+	for _, sceneName := range sceneNames {
+		if err := os.MkdirAll(contentDir+sceneName, 0755); err != nil {
+			return problems, err
+		}
+		scriptPath := contentDir + sceneName + `/script.md`
+		if err := ioutil.WriteFile(scriptPath, []byte(scripts[sceneName]), 0644); err != nil {
+			return problems, err
+		}
+		mapConfig := []byte("{\n    \"directions\": {},\n    \"look\": \"\"\n}\n")
+		if err := ioutil.WriteFile(contentDir+sceneName+`/mapConfig.json`, mapConfig, 0644); err != nil {
+			return problems, err
+		}
+
+		// Whatever the script parser doesn't understand is reported with the line in the written file
+		if _, err := parseScript(scriptPath, scripts[sceneName]); err != nil {
+			if errorList, isErrorList := err.(ScriptErrorList); isErrorList {
+				problems = append(problems, errorList...)
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,110 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertTweeStory(t *testing.T) {
+	twee := ":: StoryTitle\n" +
+		"The Lighthouse\n" +
+		"\n" +
+		":: StoryData\n" +
+		"{\"ifid\": \"D674C58C-DEFA-4F70-B7A2-27742230C0FC\", \"format\": \"Harlowe\", \"start\": \"Shore\"}\n" +
+		"\n" +
+		":: Shore {\"position\":\"100,100\"}\n" +
+		"The waves come and go.\n" +
+		"A lighthouse stands nearby.\n" +
+		"\n" +
+		"You could [[walk over->Lighthouse Door]] or [[stay|Shore]].\n" +
+		"\n" +
+		":: Lighthouse Door [scene:Lighthouse]\n" +
+		"(set: $knocked to true)The door is locked.\n" +
+		"\n" +
+		"[[Shore<-Go back]]\n" +
+		"[[Upstairs]]\n"
+
+	story, problems := readTweeStory(`story.twee`, twee)
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	scripts, problems := convertTweeStory(story, getTweeSceneNameFromTitle(story.title))
+
+	expectedScripts := map[string]string{
+		`TheLighthouse`: "# beginning\n" +
+			"The waves come and go.\n" +
+			"A lighthouse stands nearby.\n" +
+			"\n" +
+			"You could walk over or stay.\n" +
+			"\n" +
+			"`(walk over) > Lighthouse#beginning`\n" +
+			"\n" +
+			"`(stay) > beginning`\n",
+		`Lighthouse`: "# beginning\n" +
+			"<!-- Twine: (set: $knocked to true)The door is locked. -->\n" +
+			"\n" +
+			"Go back\n" +
+			"Upstairs\n" +
+			"\n" +
+			"`(Go back) > TheLighthouse#beginning`\n" +
+			"\n" +
+			"`(Upstairs)`\n",
+	}
+	for sceneName, expectedScript := range expectedScripts {
+		if scripts[sceneName] != expectedScript {
+			t.Errorf("Expected the script of '%s' to be\n%s\nbut got\n%s", sceneName, expectedScript, scripts[sceneName])
+		}
+		if _, err := parseScript(sceneName+`/script.md`, scripts[sceneName]); err != nil {
+			t.Errorf("The converted script of '%s' can't be parsed: %v", sceneName, err)
+		}
+	}
+
+	expectedProblems := []string{
+		"story.twee:14: passage 'Lighthouse Door' uses the unsupported Twine macro '(set:'",
+		"story.twee:14: passage 'Lighthouse Door' uses the unsupported Twine macro '$knocked'",
+		"story.twee:16: passage 'Lighthouse Door' links to the missing passage 'Upstairs'",
+	}
+	if len(problems) != len(expectedProblems) {
+		t.Fatalf("Expected %d problems but got:\n%v", len(expectedProblems), problems)
+	}
+	for idx, expectedProblem := range expectedProblems {
+		if !strings.HasPrefix(problems[idx].Error(), expectedProblem) {
+			t.Errorf("Expected problem starting with %q but got %q", expectedProblem, problems[idx].Error())
+		}
+	}
+}
+
+func TestTweeSceneNames(t *testing.T) {
+	if sceneName := getTweeSceneNameFromTitle(`über den Ölberg`); sceneName != `ÜberDenÖlberg` {
+		t.Errorf("Expected 'ÜberDenÖlberg' but got %q", sceneName)
+	}
+
+	twee := ":: Start\n" +
+		"The story begins.\n" +
+		"\n" +
+		":: Escape [scene:../Beach]\n" +
+		"Somewhere else.\n" +
+		"\n" +
+		":: Hidden [scene:Cellar/Secret]\n" +
+		"Deeper.\n"
+	story, problems := readTweeStory(`story.twee`, twee)
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	scripts, problems := convertTweeStory(story, `Story`)
+	if len(scripts) != 1 || scripts[`Story`] == `` {
+		t.Errorf("Expected every passage in the default scene but got the scenes %v", scripts)
+	}
+	expectedProblems := []string{
+		"story.twee:4: passage 'Escape' is tagged with the invalid scene name '../Beach'",
+		"story.twee:7: passage 'Hidden' is tagged with the invalid scene name 'Cellar/Secret'",
+	}
+	if len(problems) != len(expectedProblems) {
+		t.Fatalf("Expected %d problems but got:\n%v", len(expectedProblems), problems)
+	}
+	for idx, expectedProblem := range expectedProblems {
+		if !strings.HasPrefix(problems[idx].Error(), expectedProblem) {
+			t.Errorf("Expected problem starting with %q but got %q", expectedProblem, problems[idx].Error())
+		}
+	}
+}
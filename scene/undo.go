@@ -0,0 +1,64 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file takes back player commands so players can try things out without being stuck with the consequences.
+package scene
+
+import (
+	"fmt"
+	"strings"
+)
+
+// undoCommand takes back the last player command when it is typed, Ctrl+Z does the same
+const undoCommand = `undo`
+
+// undoLimit is the number of player commands which can be taken back
+const undoLimit = 50
+
+// globalUndoSnapshots contains a snapshot of the game before each of the last player commands, the latest one last.
+var globalUndoSnapshots []*savedGame
+
+// handlePlayerInput handles the player's input like 'handlePlayerCommand' but takes a snapshot of the game before so
+// the command can be undone. 'undo' itself restores the last snapshot.
+func (s *Scene) handlePlayerInput(playerInput string) {
+	switch strings.ToLower(strings.TrimSpace(playerInput)) {
+	case ``:
+		return
+	case undoCommand:
+		undo()
+		return
+	}
+	if unknownWord := s.getUnknownWord(playerInput); unknownWord != `` {
+		globalNarrator.setMessage(fmt.Sprintf(translate("You don't know the word '%s' yet."), unknownWord), s)
+		return
+	}
+
+	globalUndoSnapshots = append(globalUndoSnapshots, getSavedGame(s.Name))
+	if len(globalUndoSnapshots) > undoLimit {
+		globalUndoSnapshots = globalUndoSnapshots[len(globalUndoSnapshots)-undoLimit:]
+	}
+	s.handlePlayerCommand(playerInput)
+}
+
+// setMessage shows a text which isn't part of the story like 'setTextLetterByLetter' does. Undoing the next command
+// shows the story's text again instead of the message.
+func (n *Narrator) setMessage(str string, scn *Scene) {
+	storyText := n.currentTextSource
+	n.setTextLetterByLetter(str, scn)
+	n.currentTextSource = storyText
+}
+
+// undo restores the game as it has been before the last player command, including the narrator's text.
+func undo() {
+	s := GlobalScenes[GlobalCurrentScene]
+	if len(globalUndoSnapshots) == 0 {
+		globalNarrator.setMessage(translate("There is nothing to undo."), s)
+		return
+	}
+
+	snapshot := globalUndoSnapshots[len(globalUndoSnapshots)-1]
+	globalUndoSnapshots = globalUndoSnapshots[:len(globalUndoSnapshots)-1]
+	if err := restoreGame(snapshot, false); err != nil {
+		s.reportScriptError(err)
+	}
+}
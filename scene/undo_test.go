@@ -0,0 +1,57 @@
+package scene
+
+import (
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	previousScenes, previousCurrentScene, previousPreviousScene := GlobalScenes, GlobalCurrentScene, globalPreviousScene
+	previousVariables, previousGameScene := globalWorld.variables, globalGameScene
+	defer func() {
+		GlobalScenes, GlobalCurrentScene, globalPreviousScene = previousScenes, previousCurrentScene, previousPreviousScene
+		globalWorld.variables, globalGameScene = previousVariables, previousGameScene
+		globalPlayer.items = nil
+		globalUndoSnapshots = nil
+	}()
+
+	LoadFilesToSceneMap()
+	GlobalCurrentScene, globalPreviousScene = `Beach`, `Beach`
+	globalWorld.variables = map[string]string{}
+	globalPlayer.items = nil
+	globalUndoSnapshots = nil
+	beach := GlobalScenes[`Beach`]
+	if err := beach.loadActiveSection(); err != nil {
+		t.Fatal(err)
+	}
+	for beach.deliverNextResponse() {
+	}
+
+	// Change what a command could change, then take it back
+	beach.handlePlayerInput(`inspect reflection`)
+	globalWorld.variables[`hasLooked`] = `true`
+	globalPlayer.addItem(`compass`, &sceneObject{Name: `compass`})
+	beach.objects[`oldCupboard`].Attributes[`lock_state`] = `unlocked`
+	parsedScript := beach.script.parsed
+	beach.handlePlayerInput(`Undo`)
+
+	beach = GlobalScenes[`Beach`]
+	if beach.script.parsed != parsedScript {
+		t.Errorf("Expected undo to keep the script in memory instead of reading the files again")
+	}
+	if beach.progress != `beginning` || len(beach.script.responseQueue) != 0 {
+		t.Errorf("Expected to be back at the end of '# beginning' but got '# %s' with %d queued lines",
+			beach.progress, len(beach.script.responseQueue))
+	}
+	if len(globalWorld.variables) != 0 || len(globalPlayer.items) != 0 ||
+		beach.objects[`oldCupboard`].Attributes[`lock_state`] != `locked` {
+		t.Errorf("Expected the variables, inventory and objects to be restored but got %v, %v and '%v'",
+			globalWorld.variables, globalPlayer.items, beach.objects[`oldCupboard`].Attributes[`lock_state`])
+	}
+
+	for i := 0; i < undoLimit+10; i++ {
+		beach.handlePlayerInput(`dance`)
+	}
+	if len(globalUndoSnapshots) != undoLimit {
+		t.Errorf("Expected %d snapshots to be kept but got %d", undoLimit, len(globalUndoSnapshots))
+	}
+}
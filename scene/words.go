@@ -0,0 +1,156 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the word inventory. The narrator's text marks words the player collects, e.g.
+//
+//	a <span word="compass">compass</span>
+//
+// and in sections with `[WordMode: on]` the player's commands may only be made of collected words.
+package scene
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+// wordModeDirective is written as `[WordMode: on]` anywhere in a section
+const wordModeDirective = `WordMode`
+
+// collectableWordColor is used for the marked words in the narrator's text and the words in the side panel
+var collectableWordColor = colornames.Darkgoldenrod
+
+// unknownWordColor greys out the words in the player's text which haven't been collected
+var unknownWordColor = colornames.Darkgray
+
+var (
+	wordAttributeRegexp = regexp.MustCompile(`<span\s[^>]*\bword\s*=\s*"([^"]+)"[^>]*>`)
+	// wordChunkRegexp splits the player's text into words and the whitespace between them
+	wordChunkRegexp = regexp.MustCompile(`\S+|\s+`)
+)
+
+// getMarkedWords returns the words marked in a narrator text in the order they are written.
+func getMarkedWords(str string) []string {
+	var words []string
+	for _, wordMatch := range wordAttributeRegexp.FindAllStringSubmatch(stripMarkdownComments(str), -1) {
+		words = append(words, strings.ToLower(strings.TrimSpace(wordMatch[1])))
+	}
+	return words
+}
+
+func (p *Player) hasWord(word string) bool {
+	for _, collectedWord := range p.wordInventory {
+		if collectedWord == word {
+			return true
+		}
+	}
+	return false
+}
+
+// collectWords puts the words marked in the narrator text into the word inventory and returns the new ones.
+func (p *Player) collectWords(str string) []string {
+	var newWords []string
+	for _, word := range getMarkedWords(str) {
+		if word != `` && !p.hasWord(word) {
+			p.wordInventory = append(p.wordInventory, word)
+			newWords = append(newWords, word)
+		}
+	}
+	return newWords
+}
+
+// isKnownWord returns whether the player may type the word in the word mode. Articles, prepositions and 'undo' don't
+// have to be collected.
+func (p *Player) isKnownWord(word string) bool {
+	word = strings.ToLower(strings.Trim(word, `.,!?;:"'`))
+	return word == `` || articles[word] || globalVerbDictionary.prepositions[word] || word == undoCommand ||
+		p.hasWord(word)
+}
+
+// getUnknownWord returns the first word of the input the player hasn't collected or an empty string if the scene's
+// active section isn't in the word mode.
+func (s *Scene) getUnknownWord(playerInput string) string {
+	if !s.isWordMode() {
+		return ``
+	}
+	for _, word := range strings.Fields(playerInput) {
+		if !globalPlayer.isKnownWord(word) {
+			return strings.ToLower(strings.Trim(word, `.,!?;:"'`))
+		}
+	}
+	return ``
+}
+
+// isWordMode returns whether the active section has been written with `[WordMode: on]`.
+func (s *Scene) isWordMode() bool {
+	if s == nil || s.script.parsed == nil {
+		return false
+	}
+	section := s.script.parsed.sectionMap[s.progress]
+	return section != nil && section.isWordMode
+}
+
+// setWordMode sets the word mode of the current section, the directive can be written anywhere in the section.
+func (p *scriptParser) setWordMode(mode string, lineNumber int) {
+	switch mode {
+	case `on`:
+		p.currentSection.isWordMode = true
+	case `off`:
+		p.currentSection.isWordMode = false
+	default:
+		p.addError(lineNumber, "unknown word mode '%s' (known are 'on' and 'off')", mode)
+	}
+}
+
+// writeText writes the player's text, in the word mode the words which haven't been collected are greyed out.
+func (p *Player) writeText(str string, scn *Scene) {
+	textObject := p.currentTextObjects[0]
+	textObject.Clear()
+	if !scn.isWordMode() {
+		textObject.WriteString(str)
+		return
+	}
+
+	textColor := textObject.Color
+	for _, chunk := range wordChunkRegexp.FindAllString(str, -1) {
+		if !p.isKnownWord(chunk) {
+			textObject.Color = unknownWordColor
+		}
+		textObject.WriteString(chunk)
+		textObject.Color = textColor
+	}
+}
+
+// getWordPanelText lists the collected words for the side panel.
+func getWordPanelText() string {
+	return fmt.Sprintf(translate("Words: %s"), strings.Join(globalPlayer.wordInventory, `, `))
+}
+
+// drawWordPanel shows the collected words at the left between the narrator's and the player's text box, in columns
+// of five words.
+func (s *Scene) drawWordPanel(win *pixelgl.Window) {
+	const wordsPerColumn = 5
+	const columnWidth = 120 // pixels
+
+	panelTopLeft := globalNarrator.textBox.topLeftCorner.Sub(pixel.V(0, globalNarrator.textBox.dimensions.Y+30))
+	title := text.New(panelTopLeft, s.atlas)
+	title.Color = s.textColor
+	title.WriteString(translate("Words:"))
+	title.Draw(win, pixel.IM)
+
+	for column := 0; column*wordsPerColumn < len(globalPlayer.wordInventory); column++ {
+		words := globalPlayer.wordInventory[column*wordsPerColumn:]
+		if len(words) > wordsPerColumn {
+			words = words[:wordsPerColumn]
+		}
+		columnText := text.New(panelTopLeft.Add(pixel.V(float64(column*columnWidth), -title.LineHeight)), s.atlas)
+		columnText.Color = collectableWordColor
+		columnText.WriteString(strings.Join(words, "\n"))
+		columnText.Draw(win, pixel.IM)
+	}
+}
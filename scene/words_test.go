@@ -0,0 +1,64 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWordInventory(t *testing.T) {
+	previousWordInventory := globalPlayer.wordInventory
+	defer func() {
+		globalPlayer.wordInventory = previousWordInventory
+	}()
+	globalPlayer.wordInventory = nil
+
+	narratorText := `You see a <span word="Compass" style="color:red">compass</span> and ` +
+		`<!-- <span word="hidden">hidden</span> --><span word="rope">two ropes</span>.`
+	if words := globalPlayer.collectWords(narratorText); strings.Join(words, ` `) != `compass rope` {
+		t.Errorf("Expected the words 'compass rope' to be collected but got %v", words)
+	}
+	if words := globalPlayer.collectWords(narratorText); len(words) != 0 {
+		t.Errorf("Expected no new words from the same text but got %v", words)
+	}
+	markdownCommands, _ := getMarkdownCommandSliceFromString(narratorText)
+	if markdownCommands[0].attributeValueMap[`word`] != `Compass` ||
+		markdownCommands[0].attributeValueMap[`color`] != `red` {
+		t.Errorf("Expected the word and the style of the span but got %v", markdownCommands[0].attributeValueMap)
+	}
+
+	parsed, err := parseScript(`Mountain/script.md`, "# beginning\n"+
+		"`(Climb) > summit`\n"+
+		"\n"+
+		"# summit\n"+
+		"`[WordMode: on]`\n"+
+		"\n"+
+		"The wind brings words.\n"+
+		"\n"+
+		"# valley\n"+
+		"`[WordMode: sometimes]`\n")
+	if err == nil || !strings.Contains(err.Error(), `script.md:10: unknown word mode 'sometimes'`) {
+		t.Errorf("Expected the unknown word mode to be reported but got '%v'", err)
+	}
+	mountain := &Scene{progress: `beginning`}
+	mountain.script.parsed = parsed
+	if mountain.getUnknownWord(`climb`) != `` {
+		t.Errorf("Expected every word to be allowed outside of the word mode")
+	}
+
+	mountain.progress = `summit`
+	tests := []struct {
+		input               string
+		expectedUnknownWord string
+	}{
+		{`Compass!`, ``},
+		{`tie the rope to the compass`, `tie`},
+		{`rope on compass`, ``},
+		{`undo`, ``},
+	}
+	for _, test := range tests {
+		if unknownWord := mountain.getUnknownWord(test.input); unknownWord != test.expectedUnknownWord {
+			t.Errorf("Expected '%s' to have the unknown word '%s' but got '%s'", test.input,
+				test.expectedUnknownWord, unknownWord)
+		}
+	}
+}
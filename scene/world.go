@@ -0,0 +1,92 @@
+// Package scene implements functions to provide the contents of a scene
+// like the backgrounds and texts and music.
+//
+// This file contains the variables which scripts set with `[Set: ...]` and `[Add: ...]` and refer to with '{name}'.
+package scene
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// worldState contains the game state which is neither part of a scene nor of the player.
+type worldState struct {
+	// variables are set by the scripts, all values are stored as strings
+	variables map[string]string
+}
+
+var globalWorld = worldState{
+	variables: make(map[string]string),
+}
+
+var (
+	setArgumentRegexp   = regexp.MustCompile(`^(\w+)\s*=\s*(.*)$`)
+	addArgumentRegexp   = regexp.MustCompile(`^(\w+)\s+(-?\d+)$`)
+	interpolationRegexp = regexp.MustCompile(`\{(\w+)(?::(\w+))?\}`)
+)
+
+// isTruthy decides whether a variable counts as a set flag.
+func isTruthy(value string) bool {
+	switch strings.ToLower(value) {
+	case ``, `false`, `0`, `no`:
+		return false
+	}
+	return true
+}
+
+// getNumber returns the numeric value of a variable. Unset variables count as 0.
+func (w *worldState) getNumber(name string) int {
+	value := w.variables[name]
+	if value == `` {
+		return 0
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Variable '%s' with value '%s' is not a number and counts as 0", name, value)
+	}
+	return number
+}
+
+// executeSet applies a `[Set: name = value]` directive.
+func (w *worldState) executeSet(argument string) {
+	setMatch := setArgumentRegexp.FindStringSubmatch(argument)
+	w.variables[setMatch[1]] = strings.TrimSpace(setMatch[2])
+}
+
+// executeAdd applies an `[Add: name amount]` directive. The amount can be negative.
+func (w *worldState) executeAdd(argument string) {
+	addMatch := addArgumentRegexp.FindStringSubmatch(argument)
+	amount, _ := strconv.Atoi(addMatch[2])
+	w.variables[addMatch[1]] = strconv.Itoa(w.getNumber(addMatch[1]) + amount)
+}
+
+// interpolateVariables replaces '{name}' with the value of the variable, '{visited:Scene}' with the number of
+// times the scene has been entered and '{input}' with the player's last command.
+func interpolateVariables(text string) string {
+	return interpolationRegexp.ReplaceAllStringFunc(text, func(placeholder string) string {
+		placeholderMatch := interpolationRegexp.FindStringSubmatch(placeholder)
+		if placeholderMatch[1] == `visited` && placeholderMatch[2] != `` {
+			return strconv.Itoa(getVisitCount(placeholderMatch[2]))
+		}
+		if placeholderMatch[2] != `` {
+			// Unknown kind of placeholder, leave it for the writer to see
+			return placeholder
+		}
+		if placeholderMatch[1] == `input` {
+			// The player must not be able to sneak in markup
+			return strings.NewReplacer(`<`, ``, `>`, ``).Replace(globalPlayer.lastInput)
+		}
+		return globalWorld.variables[placeholderMatch[1]]
+	})
+}
+
+// getVisitCount returns the number of times the scene has been entered.
+func getVisitCount(sceneName string) int {
+	visitedScene := GlobalScenes[sceneName]
+	if visitedScene == nil || visitedScene.mapConfig == nil {
+		return 0
+	}
+	return visitedScene.mapConfig.Visited
+}